@@ -0,0 +1,1617 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geckoboard/slash-infra/search"
+	"github.com/geckoboard/slash-infra/slackutil"
+)
+
+// resultSetFormatter turns a search.ResultSet into slack attachments.
+// Results of a kind that has a template are laid out the way it says.
+type resultSetFormatter func(set search.ResultSet, templates map[string]search.ResultTemplate) []slackutil.Attachment
+
+// resultSetFormatters turn each kind of search.ResultSet into slack
+// attachments. Result sets without a formatter aren't shown.
+var resultSetFormatters = map[string]resultSetFormatter{
+	"ec2.instance":          eachResult(FormatEc2InstanceAsAttachment),
+	"ec2.network_interface": eachResult(FormatNetworkInterfaceAsAttachment),
+	"elb.load_balancer":     eachResult(FormatLoadBalancerAsAttachment),
+	"route53.chain":         withoutTemplates(FormatDNSChainAsAttachments),
+	"autoscaling.group":     eachResult(FormatAutoScalingGroupAsAttachment),
+	"ecs.task":              eachResult(FormatEcsTaskAsAttachment),
+	"ecs.service":           eachResult(FormatEcsServiceAsAttachment),
+	"lambda.function":       eachResult(FormatLambdaFunctionAsAttachment),
+	"s3.bucket":             eachResult(FormatS3BucketAsAttachment),
+	"ebs.volume":            eachResult(FormatEbsVolumeAsAttachment),
+	"ebs.snapshot":          eachResult(FormatEbsSnapshotAsAttachment),
+	"ec2.image":             eachResult(FormatImageAsAttachment),
+	"ec2.elastic_ip":        eachResult(FormatElasticIPAsAttachment),
+	"cloudformation.stack":  eachResult(FormatCloudFormationStackAsAttachment),
+	"elasticache.cluster":   eachResult(FormatCacheClusterAsAttachment),
+	"sqs.queue":             eachResult(FormatSqsQueueAsAttachment),
+	"sns.topic":             eachResult(FormatSnsTopicAsAttachment),
+	"dynamodb.table":        eachResult(FormatDynamoDBTableAsAttachment),
+	"acm.certificate":       eachResult(FormatCertificateAsAttachment),
+	"acm.expiry_report":     withoutTemplates(FormatCertificateExpiryReportAsAttachments),
+	"arn.unknown_account":   eachResult(FormatUnknownAccountAsAttachment),
+	"bulk.lookup":           withoutTemplates(FormatBulkLookupAsAttachments),
+	"ec2.instance_matches":  withoutTemplates(FormatInstanceMatchesAsAttachments),
+	"inventory.changes":     withoutTemplates(FormatInventoryChangesAsAttachments),
+	"compliance.summary":    withoutTemplates(FormatComplianceSummaryAsAttachments),
+
+	"hygiene.untagged_instances":     withoutTemplates(hygieneCheck("Instances that break the tagging policy", describeHygieneInstance)),
+	"hygiene.stopped_instances":      withoutTemplates(hygieneCheck("Instances stopped for more than "+strconv.Itoa(int(search.StoppedInstanceMaxAge.Hours()/24))+" days", describeHygieneInstance)),
+	"hygiene.unattached_volumes":     withoutTemplates(hygieneCheck("Unattached EBS volumes", describeHygieneVolume)),
+	"hygiene.unassociated_addresses": withoutTemplates(hygieneCheck("Unassociated elastic IPs", describeHygieneAddress)),
+	"hygiene.inventory_not_ready":    withoutTemplates(FormatInventoryNotReadyAsAttachments),
+}
+
+// formatResultSets turns every result set that has a formatter into
+// attachments, in order
+func formatResultSets(sets []search.ResultSet, templates map[string]search.ResultTemplate) []slackutil.Attachment {
+	attachments := []slackutil.Attachment{}
+
+	for _, set := range sets {
+		if format, ok := resultSetFormatters[set.Kind]; ok {
+			attachments = append(attachments, format(set, templates)...)
+		}
+	}
+
+	return attachments
+}
+
+// withoutTemplates is for formatters that summarise a whole set, rather
+// than showing each result's fields, so have no use for templates
+func withoutTemplates(format func(search.ResultSet) []slackutil.Attachment) resultSetFormatter {
+	return func(set search.ResultSet, templates map[string]search.ResultTemplate) []slackutil.Attachment {
+		return format(set)
+	}
+}
+
+// eachResult formats every result in a set as its own attachment
+func eachResult(format func(search.Result) slackutil.Attachment) resultSetFormatter {
+	return func(set search.ResultSet, templates map[string]search.ResultTemplate) []slackutil.Attachment {
+		attachments := []slackutil.Attachment{}
+
+		for _, result := range set.Results {
+			attachment := format(result)
+			if template, ok := templates[result.Kind]; ok {
+				attachment.Fields = templateFields(template, result)
+			}
+			attachment = withExternalLinks(attachment, result)
+
+			if stack := result.GetMetadata("cloudformation_stack"); stack != "" {
+				attachment.Fields = append(attachment.Fields, slackutil.Field{
+					Title: "CloudFormation stack",
+					Value: fmt.Sprintf(
+						"<%s|%s> `%s`, last updated %s",
+						result.GetLink("cloudformation_stack"),
+						stack,
+						result.GetMetadata("cloudformation_stack_status"),
+						result.GetMetadata("cloudformation_stack_updated_at"),
+					),
+				})
+			}
+
+			if violations := result.Metadata["policy_violations"]; len(violations) > 0 {
+				attachment.Fields = append(attachment.Fields, slackutil.Field{
+					Title: ":warning: Tagging policy",
+					Value: strings.Join(violations, "\n"),
+				})
+				if attachment.Color == "" {
+					attachment.Color = "warning"
+				}
+				attachment.MarkdownIn = append(attachment.MarkdownIn, "fields")
+			}
+
+			if indexedAt := result.GetMetadata("indexed_at"); indexedAt != "" {
+				attachment.Footer = fmt.Sprintf("%s · %s", attachment.Footer, describeFreshness(indexedAt))
+			}
+
+			attachments = append(attachments, attachment)
+		}
+
+		return attachments
+	}
+}
+
+func FormatNetworkInterfaceAsAttachment(networkInterface search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Description",
+			Value: networkInterface.GetMetadata("description"),
+		},
+	}
+
+	if instanceID := networkInterface.GetMetadata("instance_id"); instanceID != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Attached to",
+			Value: instanceID,
+			Short: true,
+		})
+	}
+	if publicIps := networkInterface.GetMetadata("public_ips"); publicIps != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Public IP(s)",
+			Value: publicIps,
+			Short: true,
+		})
+	}
+	if privateIps := networkInterface.GetMetadata("private_ips"); privateIps != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Private IP(s)",
+			Value: privateIps,
+			Short: true,
+		})
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Network interface <%s|%s> is `%s` in `%s`",
+			networkInterface.GetLink("ec2_console"),
+			networkInterface.GetMetadata("interface_id"),
+			networkInterface.GetMetadata("status"),
+			networkInterface.GetMetadata("az"),
+		),
+		Fields:     fields,
+		Footer:     networkInterface.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatLoadBalancerAsAttachment(lb search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Scheme",
+			Value: lb.GetMetadata("scheme"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "VPC",
+			Value: lb.GetMetadata("vpc_id"),
+			Short: true,
+		},
+	}
+
+	if instanceIDs := lb.GetMetadata("instance_ids"); instanceIDs != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Instances",
+			Value: instanceIDs,
+		})
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Load balancer <%s|%s> is a `%s` load balancer in `%s`",
+			lb.GetLink("ec2_console"),
+			lb.GetMetadata("name"),
+			lb.GetMetadata("type"),
+			lb.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Footer:     lb.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatAutoScalingGroupAsAttachment(group search.Result) slackutil.Attachment {
+	launchedFrom := group.GetMetadata("launch_configuration")
+	if template := group.GetMetadata("launch_template"); template != "" {
+		launchedFrom = fmt.Sprintf("%s (version %s)", template, group.GetMetadata("launch_template_version"))
+	}
+
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Desired / Min / Max",
+			Value: fmt.Sprintf(
+				"%s / %s / %s",
+				group.GetMetadata("desired_capacity"),
+				group.GetMetadata("min_size"),
+				group.GetMetadata("max_size"),
+			),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Launched from",
+			Value: launchedFrom,
+			Short: true,
+		},
+	}
+
+	if instances := group.Metadata["instances"]; len(instances) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Instances",
+			Value: strings.Join(instances, "\n"),
+		})
+	}
+	if activities := group.Metadata["recent_activities"]; len(activities) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Recent activity",
+			Value: strings.Join(activities, "\n"),
+		})
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Auto Scaling group <%s|%s> in `%s`",
+			group.GetLink("autoscaling_console"),
+			group.GetMetadata("name"),
+			group.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Footer:     group.GetMetadata("account"),
+		MarkdownIn: []string{"text", "fields"},
+	}
+}
+
+func FormatEcsTaskAsAttachment(task search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Task definition",
+			Value: task.GetMetadata("task_definition"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Launch type",
+			Value: task.GetMetadata("launch_type"),
+			Short: true,
+		},
+	}
+
+	if service := task.GetMetadata("service"); service != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Service",
+			Value: service,
+			Short: true,
+		})
+	}
+	if privateIps := task.GetMetadata("private_ips"); privateIps != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Private IP(s)",
+			Value: privateIps,
+			Short: true,
+		})
+	}
+	if containerInstance := task.GetMetadata("container_instance"); containerInstance != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Container instance",
+			Value: containerInstance,
+			Short: true,
+		})
+	}
+	fields = append(fields, slackutil.Field{
+		Title: "Images",
+		Value: strings.Join(task.Metadata["container_images"], "\n"),
+	})
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Task <%s|%s> is `%s` in cluster `%s`",
+			task.GetLink("ecs_console"),
+			task.GetMetadata("task_id"),
+			task.GetMetadata("last_status"),
+			task.GetMetadata("cluster"),
+		),
+		Fields:     fields,
+		Footer:     task.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatEcsServiceAsAttachment(service search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Desired / Running / Pending",
+			Value: fmt.Sprintf(
+				"%s / %s / %s",
+				service.GetMetadata("desired_count"),
+				service.GetMetadata("running_count"),
+				service.GetMetadata("pending_count"),
+			),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Task definition",
+			Value: service.GetMetadata("task_definition"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Images",
+			Value: strings.Join(service.Metadata["container_images"], "\n"),
+		},
+	}
+
+	if events := service.Metadata["recent_events"]; len(events) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Recent events",
+			Value: strings.Join(events, "\n"),
+		})
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Service <%s|%s> is `%s` in cluster `%s`",
+			service.GetLink("ecs_console"),
+			service.GetMetadata("name"),
+			service.GetMetadata("status"),
+			service.GetMetadata("cluster"),
+		),
+		Fields:     fields,
+		Footer:     service.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatLambdaFunctionAsAttachment(function search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Memory",
+			Value: function.GetMetadata("memory_size") + " MB",
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Timeout",
+			Value: function.GetMetadata("timeout") + "s",
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Last modified",
+			Value: function.GetMetadata("last_modified"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Code SHA256",
+			Value: function.GetMetadata("code_sha256"),
+			Short: true,
+		},
+	}
+
+	if vpcID := function.GetMetadata("vpc_id"); vpcID != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "VPC",
+			Value: fmt.Sprintf(
+				"%s\nSubnets: %s\nSecurity groups: %s",
+				vpcID,
+				function.GetMetadata("subnet_ids"),
+				function.GetMetadata("security_group_ids"),
+			),
+		})
+	}
+	if aliases := function.GetMetadata("aliases"); aliases != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Aliases",
+			Value: aliases,
+			Short: true,
+		})
+	}
+	if versions := function.GetMetadata("versions"); versions != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Recent versions",
+			Value: versions,
+			Short: true,
+		})
+	}
+	fields = append(fields, slackutil.Field{
+		Value: fmt.Sprintf("📜 <%s|CloudWatch logs>", function.GetLink("logs")),
+	})
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Function <%s|%s> runs `%s` in `%s`",
+			function.GetLink("lambda_console"),
+			function.GetMetadata("name"),
+			function.GetMetadata("runtime"),
+			function.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Footer:     function.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatS3BucketAsAttachment(bucket search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Versioning",
+			Value: bucket.GetMetadata("versioning"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Encryption",
+			Value: bucket.GetMetadata("encryption"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Bucket policy",
+			Value: bucket.GetMetadata("policy_status"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Block Public Access",
+			Value: strings.Join(bucket.Metadata["block_public_access"], "\n"),
+			Short: true,
+		},
+	}
+
+	tags := []string{}
+	for key := range bucket.Metadata {
+		if strings.HasPrefix(key, "tag:") {
+			tags = append(tags, fmt.Sprintf("%s=%s", strings.TrimPrefix(key, "tag:"), bucket.GetMetadata(key)))
+		}
+	}
+	if len(tags) > 0 {
+		sort.Strings(tags)
+		fields = append(fields, slackutil.Field{
+			Title: "Tags",
+			Value: strings.Join(tags, "\n"),
+		})
+	}
+
+	// Make public buckets stand out
+	color := ""
+	if bucket.GetMetadata("policy_status") == "Public" {
+		color = "danger"
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Bucket <%s|%s> is in `%s`",
+			bucket.GetLink("s3_console"),
+			bucket.GetMetadata("bucket"),
+			bucket.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Color:      color,
+		Footer:     fmt.Sprintf("%s (%s)", bucket.GetMetadata("account"), bucket.GetMetadata("account_id")),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatEbsVolumeAsAttachment(volume search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Size",
+			Value: volume.GetMetadata("size_gib") + " GiB",
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "IOPS",
+			Value: volume.GetMetadata("iops"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Encryption",
+			Value: describeEbsEncryption(volume),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Created",
+			Value: volume.GetMetadata("created_at"),
+			Short: true,
+		},
+	}
+
+	// Link each attachment to the instance it's attached to
+	attachments := []string{}
+	for i, instanceID := range volume.Metadata["instance_ids"] {
+		attachments = append(attachments, strings.Replace(
+			volume.Metadata["attachments"][i],
+			instanceID,
+			fmt.Sprintf("<%s|%s>", volume.GetLink("instance:"+instanceID), instanceID),
+			1,
+		))
+	}
+	if len(attachments) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Attached to",
+			Value: strings.Join(attachments, "\n"),
+		})
+	}
+	if snapshotID := volume.GetMetadata("source_snapshot_id"); snapshotID != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Created from",
+			Value: fmt.Sprintf("<%s|%s>", volume.GetLink("source_snapshot"), snapshotID),
+			Short: true,
+		})
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Volume <%s|%s> is an `%s` `%s` volume in `%s`",
+			volume.GetLink("ec2_console"),
+			volume.GetMetadata("volume_id"),
+			volume.GetMetadata("state"),
+			volume.GetMetadata("volume_type"),
+			volume.GetMetadata("az"),
+		),
+		Fields:     fields,
+		Footer:     volume.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatEbsSnapshotAsAttachment(snapshot search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Size",
+			Value: snapshot.GetMetadata("size_gib") + " GiB",
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Encryption",
+			Value: describeEbsEncryption(snapshot),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Created",
+			Value: snapshot.GetMetadata("created_at"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Owner",
+			Value: snapshot.GetMetadata("owner_id"),
+			Short: true,
+		},
+	}
+
+	if volumeID := snapshot.GetMetadata("source_volume_id"); volumeID != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Taken from",
+			Value: fmt.Sprintf("<%s|%s>", snapshot.GetLink("source_volume"), volumeID),
+			Short: true,
+		})
+	}
+	if description := snapshot.GetMetadata("description"); description != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Description",
+			Value: description,
+		})
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Snapshot <%s|%s> is `%s` in `%s`",
+			snapshot.GetLink("ec2_console"),
+			snapshot.GetMetadata("snapshot_id"),
+			snapshot.GetMetadata("state"),
+			snapshot.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Footer:     snapshot.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatImageAsAttachment(image search.Result) slackutil.Attachment {
+	usage := "No running instances in any account"
+	if running := image.Metadata["running_instances"]; len(running) > 0 {
+		usage = strings.Join(running, "\n")
+	}
+
+	// The image may have been deregistered while instances still use it
+	if image.GetMetadata("name") == "" {
+		return slackutil.Attachment{
+			Text: fmt.Sprintf(
+				"Image `%s` could not be found, it may have been deregistered or not shared with any account slash-infra can see",
+				image.GetMetadata("image_id"),
+			),
+			Fields: []slackutil.Field{
+				slackutil.Field{
+					Title: "Running instances",
+					Value: usage,
+				},
+			},
+			MarkdownIn: []string{"text"},
+		}
+	}
+
+	owner := image.GetMetadata("owner_id")
+	if alias := image.GetMetadata("owner_alias"); alias != "" {
+		owner = fmt.Sprintf("%s (%s)", alias, owner)
+	}
+
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Created",
+			Value: image.GetMetadata("created_at"),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Owner",
+			Value: owner,
+			Short: true,
+		},
+	}
+
+	if deprecation := image.GetMetadata("deprecation_time"); deprecation != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Deprecated at",
+			Value: deprecation,
+			Short: true,
+		})
+	}
+	if snapshots := image.Metadata["snapshot_ids"]; len(snapshots) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Snapshots",
+			Value: strings.Join(snapshots, "\n"),
+			Short: true,
+		})
+	}
+	fields = append(fields, slackutil.Field{
+		Title: fmt.Sprintf("Running instances (%s)", image.GetMetadata("total_running_instances")),
+		Value: usage,
+	})
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Image <%s|%s> `%s` is `%s`",
+			image.GetLink("ec2_console"),
+			image.GetMetadata("image_id"),
+			image.GetMetadata("name"),
+			image.GetMetadata("state"),
+		),
+		Fields:     fields,
+		Footer:     image.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatElasticIPAsAttachment(address search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Allocation ID",
+			Value: address.GetMetadata("allocation_id"),
+			Short: true,
+		},
+	}
+
+	var association string
+	switch address.GetMetadata("association_type") {
+	case "instance":
+		association = fmt.Sprintf("Instance <%s|%s>", address.GetLink("instance"), address.GetMetadata("instance_id"))
+	case "nat_gateway":
+		association = fmt.Sprintf(
+			"NAT gateway <%s|%s> is `%s` in `%s`",
+			address.GetLink("nat_gateway"),
+			address.GetMetadata("nat_gateway_id"),
+			address.GetMetadata("nat_gateway_state"),
+			address.GetMetadata("vpc_id"),
+		)
+	case "network_interface":
+		association = fmt.Sprintf("Network interface <%s|%s>", address.GetLink("network_interface"), address.GetMetadata("interface_id"))
+	default:
+		association = "Nothing, this address is costing money without being used"
+	}
+	fields = append(fields, slackutil.Field{
+		Title: "Associated with",
+		Value: association,
+		Short: true,
+	})
+
+	if privateIps := address.GetMetadata("private_ips"); privateIps != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Private IP",
+			Value: privateIps,
+			Short: true,
+		})
+	}
+
+	// Unassociated addresses are billed for, so make them stand out
+	color := ""
+	if address.GetMetadata("associated") != "true" {
+		color = "warning"
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Elastic IP <%s|%s> in `%s`",
+			address.GetLink("ec2_console"),
+			address.GetMetadata("public_ip"),
+			address.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Color:      color,
+		Footer:     address.GetMetadata("account"),
+		MarkdownIn: []string{"text", "fields"},
+	}
+}
+
+func FormatCloudFormationStackAsAttachment(stack search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Last updated",
+			Value: stack.GetMetadata("updated_at"),
+			Short: true,
+		},
+	}
+
+	if reason := stack.GetMetadata("status_reason"); reason != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Status reason",
+			Value: reason,
+			Short: true,
+		})
+	}
+	if description := stack.GetMetadata("description"); description != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Description",
+			Value: description,
+		})
+	}
+
+	resources := stack.Metadata["resources"]
+	fields = append(fields, slackutil.Field{
+		Title: fmt.Sprintf("Resources (%d)", len(resources)),
+		Value: strings.Join(resources, "\n"),
+	})
+
+	// Failed and rolled back stacks need someone to look at them
+	color := ""
+	if status := stack.GetMetadata("status"); strings.Contains(status, "FAILED") || strings.Contains(status, "ROLLBACK") {
+		color = "danger"
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Stack <%s|%s> is `%s` in `%s`",
+			stack.GetLink("cloudformation_console"),
+			stack.GetMetadata("stack_name"),
+			stack.GetMetadata("status"),
+			stack.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Color:      color,
+		Footer:     stack.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatCacheClusterAsAttachment(cluster search.Result) slackutil.Attachment {
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Engine",
+			Value: fmt.Sprintf("%s %s", cluster.GetMetadata("engine"), cluster.GetMetadata("engine_version")),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Node type",
+			Value: fmt.Sprintf("%s × %s", cluster.GetMetadata("num_nodes"), cluster.GetMetadata("node_type")),
+			Short: true,
+		},
+	}
+
+	if endpoint := cluster.GetMetadata("endpoint"); endpoint != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Endpoint",
+			Value: endpoint,
+			Short: true,
+		})
+	}
+	if description := cluster.GetMetadata("description"); description != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Description",
+			Value: description,
+			Short: true,
+		})
+	}
+	if nodes := cluster.Metadata["nodes"]; len(nodes) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Nodes",
+			Value: strings.Join(nodes, "\n"),
+			Short: true,
+		})
+	}
+
+	kind := "Cache cluster"
+	if cluster.GetMetadata("cluster_id") == cluster.GetMetadata("replication_group_id") {
+		kind = "Replication group"
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"%s <%s|%s> is `%s` in `%s`",
+			kind,
+			cluster.GetLink("elasticache_console"),
+			cluster.GetMetadata("cluster_id"),
+			cluster.GetMetadata("status"),
+			cluster.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Footer:     cluster.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatSqsQueueAsAttachment(queue search.Result) slackutil.Attachment {
+	deadLetterQueue := "None"
+	if target := queue.GetMetadata("dead_letter_queue"); target != "" {
+		deadLetterQueue = fmt.Sprintf("%s after %s receives", target, queue.GetMetadata("max_receive_count"))
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Queue <%s|%s> in `%s`",
+			queue.GetLink("sqs_console"),
+			queue.GetMetadata("queue_name"),
+			queue.GetMetadata("region"),
+		),
+		Fields: []slackutil.Field{
+			slackutil.Field{
+				Title: "Messages",
+				Value: fmt.Sprintf(
+					"%s available, %s in flight, %s delayed",
+					queue.GetMetadata("messages_available"),
+					queue.GetMetadata("messages_in_flight"),
+					queue.GetMetadata("messages_delayed"),
+				),
+				Short: true,
+			},
+			slackutil.Field{
+				Title: "Retention",
+				Value: queue.GetMetadata("retention"),
+				Short: true,
+			},
+			slackutil.Field{
+				Title: "Visibility timeout",
+				Value: queue.GetMetadata("visibility_timeout"),
+				Short: true,
+			},
+			slackutil.Field{
+				Title: "Dead letter queue",
+				Value: deadLetterQueue,
+				Short: true,
+			},
+		},
+		Footer:     queue.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatSnsTopicAsAttachment(topic search.Result) slackutil.Attachment {
+	subscriptions := "None"
+	if endpoints := topic.Metadata["subscriptions"]; len(endpoints) > 0 {
+		subscriptions = strings.Join(endpoints, "\n")
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Topic <%s|%s> in `%s`",
+			topic.GetLink("sns_console"),
+			topic.GetMetadata("topic_name"),
+			topic.GetMetadata("region"),
+		),
+		Fields: []slackutil.Field{
+			slackutil.Field{
+				Title: fmt.Sprintf(
+					"Subscriptions (%s confirmed, %s pending)",
+					topic.GetMetadata("subscriptions_confirmed"),
+					topic.GetMetadata("subscriptions_pending"),
+				),
+				Value: subscriptions,
+			},
+		},
+		Footer:     topic.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatDynamoDBTableAsAttachment(table search.Result) slackutil.Attachment {
+	capacity := "On-demand"
+	if table.GetMetadata("billing_mode") != "PAY_PER_REQUEST" {
+		capacity = fmt.Sprintf(
+			"Provisioned, %s RCU / %s WCU",
+			table.GetMetadata("read_capacity"),
+			table.GetMetadata("write_capacity"),
+		)
+	}
+
+	stream := "Disabled"
+	if viewType := table.GetMetadata("stream"); viewType != "" {
+		stream = viewType
+	}
+
+	ttl := "Disabled"
+	if attribute := table.GetMetadata("ttl_attribute"); attribute != "" {
+		ttl = fmt.Sprintf("`%s`", attribute)
+	}
+
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Capacity",
+			Value: capacity,
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Items",
+			Value: fmt.Sprintf("%s (%s)", table.GetMetadata("item_count"), formatBytes(table.GetMetadata("size_bytes"))),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "TTL attribute",
+			Value: ttl,
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Stream",
+			Value: stream,
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Point in time recovery",
+			Value: table.GetMetadata("point_in_time_recovery"),
+			Short: true,
+		},
+	}
+
+	if indexes := table.Metadata["global_secondary_indexes"]; len(indexes) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Global secondary indexes",
+			Value: strings.Join(indexes, "\n"),
+			Short: true,
+		})
+	}
+	if replicas := table.Metadata["replicas"]; len(replicas) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Global table replicas",
+			Value: strings.Join(replicas, "\n"),
+			Short: true,
+		})
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Table <%s|%s> is `%s` in `%s`",
+			table.GetLink("dynamodb_console"),
+			table.GetMetadata("table_name"),
+			table.GetMetadata("status"),
+			table.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Footer:     table.GetMetadata("account"),
+		MarkdownIn: []string{"text", "fields"},
+	}
+}
+
+func FormatCertificateAsAttachment(certificate search.Result) slackutil.Attachment {
+	inUseBy := "Nothing"
+	if arns := certificate.Metadata["in_use_by"]; len(arns) > 0 {
+		inUseBy = strings.Join(arns, "\n")
+	}
+
+	fields := []slackutil.Field{
+		slackutil.Field{
+			Title: "Expires",
+			Value: fmt.Sprintf("%s (%s days)", certificate.GetMetadata("expires_at"), certificate.GetMetadata("days_until_expiry")),
+			Short: true,
+		},
+		slackutil.Field{
+			Title: "Renewal eligibility",
+			Value: certificate.GetMetadata("renewal_eligibility"),
+			Short: true,
+		},
+	}
+
+	if renewal := certificate.GetMetadata("renewal_status"); renewal != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Renewal status",
+			Value: renewal,
+			Short: true,
+		})
+	}
+	if names := certificate.Metadata["subject_alternative_names"]; len(names) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Alternative names",
+			Value: strings.Join(names, "\n"),
+			Short: true,
+		})
+	}
+	fields = append(fields, slackutil.Field{
+		Title: "In use by",
+		Value: inUseBy,
+	})
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"%s certificate <%s|%s> is `%s` in `%s`",
+			certificate.GetMetadata("type"),
+			certificate.GetLink("acm_console"),
+			certificate.GetMetadata("domain_name"),
+			certificate.GetMetadata("status"),
+			certificate.GetMetadata("region"),
+		),
+		Fields:     fields,
+		Color:      certificateExpiryColor(certificate),
+		Footer:     certificate.GetMetadata("account"),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+// FormatCertificateExpiryReportAsAttachments lists every expiring
+// certificate on one line, soonest first
+func FormatCertificateExpiryReportAsAttachments(set search.ResultSet) []slackutil.Attachment {
+	if len(set.Results) == 0 {
+		return []slackutil.Attachment{
+			slackutil.Attachment{
+				Text:  "No certificates are about to expire :tada:",
+				Color: "good",
+			},
+		}
+	}
+
+	attachments := []slackutil.Attachment{}
+	for _, certificate := range set.Results {
+		usage := "not in use"
+		if inUse := len(certificate.Metadata["in_use_by"]); inUse > 0 {
+			usage = fmt.Sprintf("used by %d resources", inUse)
+		}
+
+		attachments = append(attachments, slackutil.Attachment{
+			Text: fmt.Sprintf(
+				"<%s|%s> expires in %s days (%s), renewal %s, %s",
+				certificate.GetLink("acm_console"),
+				certificate.GetMetadata("domain_name"),
+				certificate.GetMetadata("days_until_expiry"),
+				certificate.GetMetadata("expires_at"),
+				strings.ToLower(certificate.GetMetadata("renewal_eligibility")),
+				usage,
+			),
+			Color:      certificateExpiryColor(certificate),
+			Footer:     fmt.Sprintf("%s (%s)", certificate.GetMetadata("account"), certificate.GetMetadata("region")),
+			MarkdownIn: []string{"text"},
+		})
+	}
+
+	return attachments
+}
+
+// certificateExpiryColor makes certificates that are about to expire
+// stand out
+func certificateExpiryColor(certificate search.Result) string {
+	days, err := strconv.Atoi(certificate.GetMetadata("days_until_expiry"))
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case days < 7:
+		return "danger"
+	case days < search.DefaultCertificateExpiryWindowDays:
+		return "warning"
+	default:
+		return ""
+	}
+}
+
+func FormatUnknownAccountAsAttachment(unknown search.Result) slackutil.Attachment {
+	text := fmt.Sprintf(
+		"`%s` is in AWS account `%s`, which slash-infra doesn't have a role for",
+		unknown.GetMetadata("arn"),
+		unknown.GetMetadata("account_id"),
+	)
+	if unknown.GetMetadata("known_account") == "true" {
+		text = fmt.Sprintf(
+			"`%s` is in `%s`, which slash-infra doesn't search in AWS account `%s`",
+			unknown.GetMetadata("arn"),
+			unknown.GetMetadata("region"),
+			unknown.GetMetadata("account_id"),
+		)
+	}
+
+	return slackutil.Attachment{
+		Text: text,
+		Fields: []slackutil.Field{
+			slackutil.Field{
+				Title: "Accounts slash-infra can see",
+				Value: strings.Join(unknown.Metadata["visible_accounts"], "\n"),
+			},
+		},
+		Color:      "warning",
+		MarkdownIn: []string{"text"},
+	}
+}
+
+// resultIdentifierKeys is the metadata that identifies each kind of
+// result in compact listings
+var resultIdentifierKeys = map[string]string{
+	"ec2.instance":          "instance_id",
+	"ec2.network_interface": "interface_id",
+	"ec2.elastic_ip":        "allocation_id",
+	"ec2.image":             "image_id",
+	"ebs.volume":            "volume_id",
+	"ebs.snapshot":          "snapshot_id",
+	"elb.load_balancer":     "name",
+	"autoscaling.group":     "name",
+	"ecs.task":              "task_id",
+	"ecs.service":           "name",
+	"lambda.function":       "name",
+	"s3.bucket":             "bucket",
+	"elasticache.cluster":   "cluster_id",
+	"sqs.queue":             "queue_name",
+	"sns.topic":             "topic_name",
+	"dynamodb.table":        "table_name",
+	"acm.certificate":       "domain_name",
+	"cloudformation.stack":  "stack_name",
+}
+
+// resultStateKeys are the metadata keys different kinds of result use for
+// their state, in order of preference
+var resultStateKeys = []string{"instance_state", "last_status", "state", "status"}
+
+// FormatBulkLookupAsAttachments shows everything found in a pasted blob
+// of text as a table, one row per resource
+func FormatBulkLookupAsAttachments(set search.ResultSet) []slackutil.Attachment {
+	rows := [][]string{
+		[]string{"QUERY", "KIND", "RESOURCE", "STATE", "ACCOUNT"},
+	}
+
+	for _, result := range set.Results {
+		if result.Kind == "bulk.not_found" {
+			rows = append(rows, []string{result.GetMetadata("token"), "-", "not found", "", ""})
+			continue
+		}
+
+		state := ""
+		for _, key := range resultStateKeys {
+			if state = result.GetMetadata(key); state != "" {
+				break
+			}
+		}
+
+		rows = append(rows, []string{
+			result.GetMetadata("token"),
+			result.Kind,
+			result.GetMetadata(resultIdentifierKeys[result.Kind]),
+			state,
+			result.GetMetadata("account"),
+		})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	lines := []string{}
+	for _, row := range rows {
+		cells := []string{}
+		for i, cell := range row {
+			cells = append(cells, cell+strings.Repeat(" ", widths[i]-len(cell)))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+
+	return []slackutil.Attachment{
+		slackutil.Attachment{
+			Text:       fmt.Sprintf("```\n%s\n```", strings.Join(lines, "\n")),
+			MarkdownIn: []string{"text"},
+		},
+	}
+}
+
+// FormatInstanceMatchesAsAttachments lists the candidates for a partial or
+// misspelt instance ID or name, best match first
+func FormatInstanceMatchesAsAttachments(set search.ResultSet) []slackutil.Attachment {
+	lines := []string{}
+	for _, instance := range set.Results {
+		lines = append(lines, fmt.Sprintf(
+			"<%s|%s> `%s` `%s` `%s` in %s (%s)",
+			instance.GetLink("ec2_console"),
+			instance.GetMetadata("instance_id"),
+			instance.GetMetadata("tag:Name"),
+			instance.GetMetadata("instance_state"),
+			instance.GetMetadata("instance_type"),
+			instance.GetMetadata("account"),
+			instance.GetMetadata("match"),
+		))
+	}
+
+	footer := ""
+	if len(set.Results) > 0 {
+		footer = describeFreshness(set.Results[0].GetMetadata("indexed_at"))
+	}
+
+	return []slackutil.Attachment{
+		slackutil.Attachment{
+			Title:      "Did you mean one of these instances?",
+			Text:       strings.Join(lines, "\n"),
+			Footer:     footer,
+			MarkdownIn: []string{"text"},
+		},
+	}
+}
+
+// MaxAttachmentButtons is the most buttons Slack shows on an attachment
+const MaxAttachmentButtons = 5
+
+// withExternalLinks adds a button for each link to another tool that was
+// added from the link templates
+func withExternalLinks(attachment slackutil.Attachment, result search.Result) slackutil.Attachment {
+	for _, name := range result.Metadata["external_links"] {
+		if len(attachment.Actions) == MaxAttachmentButtons {
+			break
+		}
+
+		attachment.Actions = append(attachment.Actions, slackutil.Action{
+			Type: "button",
+			Text: name,
+			URL:  result.GetLink(name),
+		})
+	}
+
+	if len(attachment.Actions) > 0 {
+		attachment.CallbackID = "external_links"
+	}
+
+	return attachment
+}
+
+// ChangesToShow caps how many changes of each kind are listed, so that a
+// big deploy doesn't produce a wall of text
+const ChangesToShow = 20
+
+// changeTitles describe each kind of change to an instance
+var changeTitles = map[string]string{
+	search.ChangeLaunched:       "Launched",
+	search.ChangeTerminated:     "Terminated",
+	search.ChangeInstanceType:   "Instance type changed",
+	search.ChangeSecurityGroups: "Security groups changed",
+	search.ChangeTags:           "Tags changed",
+}
+
+func FormatInventoryChangesAsAttachments(set search.ResultSet) []slackutil.Attachment {
+	if len(set.Results) == 0 {
+		return []slackutil.Attachment{
+			slackutil.Attachment{
+				Text:  "Nothing has changed :zzz:",
+				Color: "good",
+			},
+		}
+	}
+
+	byKind := map[string][]search.Result{}
+	for _, change := range set.Results {
+		kind := change.GetMetadata("change")
+		byKind[kind] = append(byKind[kind], change)
+	}
+
+	summary := []string{}
+	attachments := []slackutil.Attachment{}
+	for _, kind := range search.ChangeKinds {
+		changes := byKind[kind]
+		if len(changes) == 0 {
+			continue
+		}
+
+		summary = append(summary, fmt.Sprintf("%d %s", len(changes), strings.ToLower(changeTitles[kind])))
+
+		lines := []string{}
+		for i, change := range changes {
+			if i == ChangesToShow {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(changes)-ChangesToShow))
+				break
+			}
+
+			line := fmt.Sprintf(
+				"<%s|%s> `%s` in %s",
+				change.GetLink("ec2_console"),
+				change.GetMetadata("instance_id"),
+				change.GetMetadata("tag:Name"),
+				change.GetMetadata("account"),
+			)
+			if details := change.Metadata["details"]; len(details) > 0 {
+				line = fmt.Sprintf("%s: %s", line, strings.Join(details, ", "))
+			}
+
+			lines = append(lines, line)
+		}
+
+		color := ""
+		if kind == search.ChangeTerminated {
+			color = "warning"
+		}
+
+		attachments = append(attachments, slackutil.Attachment{
+			Title:      changeTitles[kind],
+			Text:       strings.Join(lines, "\n"),
+			Color:      color,
+			MarkdownIn: []string{"text"},
+		})
+	}
+
+	// Accounts may have been compared over different windows if some of
+	// them don't have snapshots going back far enough
+	since := set.Results[0].GetMetadata("since")
+	for _, change := range set.Results {
+		if changeSince := change.GetMetadata("since"); changeSince < since {
+			since = changeSince
+		}
+	}
+
+	header := slackutil.Attachment{
+		Text:       fmt.Sprintf("Since %s: %s", since, strings.Join(summary, ", ")),
+		MarkdownIn: []string{"text"},
+	}
+
+	return append([]slackutil.Attachment{header}, attachments...)
+}
+
+// watchEventTitles describe each kind of event posted to watching channels
+var watchEventTitles = map[string]string{
+	search.WatchStateChanged:      ":arrows_counterclockwise: changed state",
+	search.WatchTerminated:        ":skull: was terminated",
+	search.WatchStatusCheckFailed: ":rotating_light: failed a status check",
+}
+
+func FormatWatchEventAsAttachment(subscription search.Subscription, event search.Result) slackutil.Attachment {
+	color := "warning"
+	if event.GetMetadata("event") == search.WatchStatusCheckFailed {
+		color = "danger"
+	}
+
+	return slackutil.Attachment{
+		Text: fmt.Sprintf(
+			"Instance <%s|%s> `%s` %s: %s",
+			event.GetLink("ec2_console"),
+			event.GetMetadata("instance_id"),
+			event.GetMetadata("tag:Name"),
+			watchEventTitles[event.GetMetadata("event")],
+			event.GetMetadata("details"),
+		),
+		Color:      color,
+		Footer:     fmt.Sprintf("%s · watching %s · %s", event.GetMetadata("account"), subscription.Target, describeFreshness(event.GetMetadata("indexed_at"))),
+		MarkdownIn: []string{"text"},
+	}
+}
+
+func FormatComplianceSummaryAsAttachments(set search.ResultSet) []slackutil.Attachment {
+	if len(set.Results) == 0 {
+		return []slackutil.Attachment{
+			slackutil.Attachment{
+				Text: "There's nothing in the inventory to check yet",
+			},
+		}
+	}
+
+	attachments := []slackutil.Attachment{}
+	for _, account := range set.Results {
+		instances, _ := strconv.Atoi(account.GetMetadata("instances"))
+		compliant, _ := strconv.Atoi(account.GetMetadata("compliant"))
+
+		color := "good"
+		if compliant < instances {
+			color = "warning"
+		}
+
+		percentage := 100
+		if instances > 0 {
+			percentage = compliant * 100 / instances
+		}
+
+		text := fmt.Sprintf("%d of %d instances follow the tagging policy (%d%%)", compliant, instances, percentage)
+		if violations := account.Metadata["violations_by_tag"]; len(violations) > 0 {
+			text = fmt.Sprintf("%s\nInstances with a missing or invalid tag, by tag: %s", text, strings.Join(violations, ", "))
+		}
+
+		attachments = append(attachments, slackutil.Attachment{
+			Title:  fmt.Sprintf("%s (%s)", account.GetMetadata("account"), account.GetMetadata("region")),
+			Text:   text,
+			Color:  color,
+			Footer: describeFreshness(account.GetMetadata("indexed_at")),
+		})
+	}
+
+	return attachments
+}
+
+func FormatInventoryNotReadyAsAttachments(set search.ResultSet) []slackutil.Attachment {
+	return []slackutil.Attachment{
+		slackutil.Attachment{
+			Text:  "The inventory isn't ready yet, so there's nothing to check. Try again once it's been crawled",
+			Color: "warning",
+		},
+	}
+}
+
+// HygieneItemsToShow caps how many resources each hygiene check lists
+const HygieneItemsToShow = 30
+
+// hygieneCheck formats the results of a hygiene check as one attachment,
+// with a line for each resource it found
+func hygieneCheck(title string, describe func(search.Result) string) func(search.ResultSet) []slackutil.Attachment {
+	return func(set search.ResultSet) []slackutil.Attachment {
+		if len(set.Results) == 0 {
+			return []slackutil.Attachment{
+				slackutil.Attachment{
+					Title: title,
+					Text:  "None :tada:",
+					Color: "good",
+				},
+			}
+		}
+
+		lines := []string{}
+		for i, result := range set.Results {
+			if i == HygieneItemsToShow {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(set.Results)-HygieneItemsToShow))
+				break
+			}
+
+			lines = append(lines, fmt.Sprintf("%s in %s (%s)", describe(result), result.GetMetadata("account"), result.GetMetadata("region")))
+		}
+
+		return []slackutil.Attachment{
+			slackutil.Attachment{
+				Title:      fmt.Sprintf("%s (%d)", title, len(set.Results)),
+				Text:       strings.Join(lines, "\n"),
+				Color:      "warning",
+				Footer:     describeFreshness(set.Results[0].GetMetadata("indexed_at")),
+				MarkdownIn: []string{"text"},
+			},
+		}
+	}
+}
+
+func describeHygieneInstance(instance search.Result) string {
+	description := fmt.Sprintf(
+		"<%s|%s> `%s` `%s`",
+		instance.GetLink("ec2_console"),
+		instance.GetMetadata("instance_id"),
+		instance.GetMetadata("tag:Name"),
+		instance.GetMetadata("instance_state"),
+	)
+
+	if stoppedAt, err := time.Parse(time.RFC3339, instance.GetMetadata("stopped_at")); err == nil {
+		description = fmt.Sprintf("%s since %s", description, stoppedAt.Format("2006-01-02"))
+	}
+	if violations := instance.Metadata["policy_violations"]; len(violations) > 0 {
+		description = fmt.Sprintf("%s: %s", description, strings.Join(violations, ", "))
+	}
+
+	return description
+}
+
+func describeHygieneVolume(volume search.Result) string {
+	return fmt.Sprintf(
+		"<%s|%s> `%s` %s GiB `%s`, created %s",
+		volume.GetLink("ec2_console"),
+		volume.GetMetadata("volume_id"),
+		volume.GetMetadata("tag:Name"),
+		volume.GetMetadata("size_gib"),
+		volume.GetMetadata("volume_type"),
+		volume.GetMetadata("created_at"),
+	)
+}
+
+func describeHygieneAddress(address search.Result) string {
+	return fmt.Sprintf(
+		"<%s|%s> `%s`",
+		address.GetLink("ec2_console"),
+		address.GetMetadata("public_ip"),
+		address.GetMetadata("allocation_id"),
+	)
+}
+
+// describeFreshness says how old the inventory a result came from is
+func describeFreshness(indexedAt string) string {
+	takenAt, err := time.Parse(time.RFC3339, indexedAt)
+	if err != nil {
+		return "from inventory"
+	}
+
+	return fmt.Sprintf("from inventory, %s old", time.Since(takenAt).Round(time.Second))
+}
+
+// formatBytes turns a size in bytes into something readable, e.g. "1.5 GiB"
+func formatBytes(size string) string {
+	bytes, err := strconv.ParseFloat(size, 64)
+	if err != nil {
+		return size
+	}
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
+
+func describeEbsEncryption(result search.Result) string {
+	if result.GetMetadata("encrypted") != "true" {
+		return "Not encrypted"
+	}
+
+	if keyID := result.GetMetadata("kms_key_id"); keyID != "" {
+		return fmt.Sprintf("Encrypted with %s", keyID)
+	}
+
+	return "Encrypted"
+}
+
+// FormatDNSChainAsAttachments shows each hop from a hostname to the
+// resources behind it on its own line, indented by how far down the chain
+// it is
+func FormatDNSChainAsAttachments(chain search.ResultSet) []slackutil.Attachment {
+	lines := []string{}
+
+	for _, hop := range chain.Results {
+		depth, _ := strconv.Atoi(hop.GetMetadata("hop"))
+
+		prefix := ""
+		if depth > 0 {
+			prefix = strings.Repeat("        ", depth-1) + "↳ "
+		}
+
+		lines = append(lines, fmt.Sprintf("%s`%s` %s", prefix, hop.GetMetadata("resolver"), describeDNSHop(hop)))
+	}
+
+	return []slackutil.Attachment{
+		slackutil.Attachment{
+			Text:       strings.Join(lines, "\n"),
+			MarkdownIn: []string{"text"},
+		},
+	}
+}
+
+func describeDNSHop(hop search.Result) string {
+	switch hop.Kind {
+	case "route53.record":
+		target := hop.GetMetadata("values")
+		if alias := hop.GetMetadata("alias_target"); alias != "" {
+			target = "alias " + alias
+		}
+
+		zoneType := "public"
+		if hop.GetMetadata("private_zone") == "true" {
+			zoneType = "private"
+		}
+
+		return fmt.Sprintf(
+			"<%s|%s> `%s` → %s (%s zone in %s)",
+			hop.GetLink("route53_console"),
+			hop.GetMetadata("name"),
+			hop.GetMetadata("type"),
+			target,
+			zoneType,
+			hop.GetMetadata("account"),
+		)
+	case "elb.load_balancer":
+		return fmt.Sprintf(
+			"%s load balancer <%s|%s> in %s",
+			hop.GetMetadata("type"),
+			hop.GetLink("ec2_console"),
+			hop.GetMetadata("name"),
+			hop.GetMetadata("account"),
+		)
+	case "ec2.instance":
+		return fmt.Sprintf(
+			"instance <%s|%s> `%s` `%s` in %s",
+			hop.GetLink("ec2_console"),
+			hop.GetMetadata("instance_id"),
+			hop.GetMetadata("instance_state"),
+			hop.GetMetadata("instance_type"),
+			hop.GetMetadata("account"),
+		)
+	case "ec2.network_interface":
+		return fmt.Sprintf(
+			"network interface <%s|%s> (%s) in %s",
+			hop.GetLink("ec2_console"),
+			hop.GetMetadata("interface_id"),
+			hop.GetMetadata("description"),
+			hop.GetMetadata("account"),
+		)
+	default:
+		return fmt.Sprintf("%s (not found in any account slash-infra can see)", hop.GetMetadata("target"))
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+
+	bugsnag "github.com/bugsnag/bugsnag-go"
+	"github.com/geckoboard/slash-infra/search"
+	"github.com/geckoboard/slash-infra/slackutil"
+)
+
+// startHygieneReports posts a hygiene report on HYGIENE_REPORT_SCHEDULE,
+// which can hold several schedules separated by `;`. Reports go to each of
+// the comma separated HYGIENE_REPORT_CHANNEL with SLACK_BOT_TOKEN, and to
+// the incoming webhook HYGIENE_REPORT_WEBHOOK_URL. HYGIENE_REPORT_CHECKS
+// picks which checks to run, e.g. `stopped,volumes`; by default they all
+// are. Reports aren't posted at all if any of these are invalid.
+func startHygieneReports(hygiene *search.HygieneResolver, templates map[string]search.ResultTemplate) {
+	setting := os.Getenv("HYGIENE_REPORT_SCHEDULE")
+	if setting == "" {
+		return
+	}
+
+	schedules := []schedule{}
+	for _, spec := range strings.Split(setting, ";") {
+		when, err := parseSchedule(spec)
+		if err != nil {
+			log.Printf("hygiene reports are turned off: %s", err)
+			return
+		}
+		schedules = append(schedules, when)
+	}
+
+	posters := hygieneReportPosters()
+	if len(posters) == 0 {
+		log.Print("HYGIENE_REPORT_SCHEDULE is set, but there's nowhere to post reports to. Set HYGIENE_REPORT_CHANNEL or HYGIENE_REPORT_WEBHOOK_URL")
+		return
+	}
+
+	checks := []string{}
+	if setting := os.Getenv("HYGIENE_REPORT_CHECKS"); setting != "" {
+		for _, check := range strings.Split(setting, ",") {
+			check = strings.ToLower(strings.TrimSpace(check))
+			if !isHygieneCheck(check) {
+				log.Printf("hygiene reports are turned off: HYGIENE_REPORT_CHECKS has an unknown check %q, it should be some of %s", check, strings.Join(search.HygieneChecks, ","))
+				return
+			}
+			checks = append(checks, check)
+		}
+	}
+
+	report := func() {
+		ctx := context.Background()
+
+		msg := slackutil.Message{
+			Text:        ":broom: Hygiene report",
+			Attachments: formatResultSets(hygiene.Report(ctx, checks), templates),
+		}
+
+		for _, post := range posters {
+			if err := post(ctx, msg); err != nil {
+				bugsnag.Notify(err)
+				log.Print(err)
+			}
+		}
+	}
+
+	for _, when := range schedules {
+		go runOnSchedule(context.Background(), when, report)
+	}
+}
+
+func isHygieneCheck(check string) bool {
+	for _, known := range search.HygieneChecks {
+		if check == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hygieneReportPosters returns a poster for the webhook and for each
+// channel that reports should go to
+func hygieneReportPosters() []func(context.Context, slackutil.Message) error {
+	posters := []func(context.Context, slackutil.Message) error{}
+
+	if webhookURL := os.Getenv("HYGIENE_REPORT_WEBHOOK_URL"); webhookURL != "" {
+		posters = append(posters, func(ctx context.Context, msg slackutil.Message) error {
+			return slackutil.PostToWebhook(ctx, webhookURL, msg)
+		})
+	}
+
+	channels, token := os.Getenv("HYGIENE_REPORT_CHANNEL"), os.Getenv("SLACK_BOT_TOKEN")
+	if channels == "" || token == "" {
+		return posters
+	}
+
+	client := slackutil.NewClient(token)
+	for _, channel := range strings.Split(channels, ",") {
+		channel := strings.TrimSpace(channel)
+		posters = append(posters, func(ctx context.Context, msg slackutil.Message) error {
+			msg.Channel = channel
+			return client.PostMessage(ctx, msg)
+		})
+	}
+
+	return posters
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a cron-style schedule, e.g. `0 9 * * 1-5` for 9am on
+// weekdays. Times are in UTC.
+type schedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+
+	// Like cron, if both the day of the month and the day of the week are
+	// restricted then a time only has to match one of them
+	anyDay     bool
+	anyWeekday bool
+}
+
+// parseSchedule understands the five standard cron fields, each of which
+// can be `*`, a number, a range (`1-5`), a step (`*/15`, or `5/15` to
+// start from 5) or a list of any of those (`0,30`)
+func parseSchedule(spec string) (schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return schedule{}, fmt.Errorf("schedule %q should have 5 fields: minute hour day month weekday", spec)
+	}
+
+	bounds := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	parsed := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		values, err := parseScheduleField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return schedule{}, fmt.Errorf("could not parse schedule %q: %s", spec, err)
+		}
+		parsed[i] = values
+	}
+
+	return schedule{
+		minutes:    parsed[0],
+		hours:      parsed[1],
+		days:       parsed[2],
+		months:     parsed[3],
+		weekdays:   parsed[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+func parseScheduleField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+		step, stepped := 1, false
+		if i := strings.Index(part, "/"); i != -1 {
+			stepped = true
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("bad step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("bad value %q", part)
+			}
+			// Like cron, a step from a single value runs to the end of the
+			// field, so `5/15` is 5, 20, 35 and 50
+			to = from
+			if stepped {
+				to = max
+			}
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("bad range %q", part)
+				}
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// matches is true if the schedule should run in the minute containing t
+func (s schedule) matches(t time.Time) bool {
+	t = t.UTC()
+
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+
+	day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// runOnSchedule calls fn every time the schedule matches, until ctx is
+// cancelled
+func runOnSchedule(ctx context.Context, s schedule, fn func()) {
+	for {
+		// Wake up at the start of each minute
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		if s.matches(next) {
+			fn()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    string
+		minutes []int
+		ok      bool
+	}{
+		{"Every minute", "* * * * *", nil, true},
+		{"A list", "0,30 * * * *", []int{0, 30}, true},
+		{"A range", "10-12 * * * *", []int{10, 11, 12}, true},
+		{"A step over every value", "*/15 * * * *", []int{0, 15, 30, 45}, true},
+		{"A step from a value", "5/15 * * * *", []int{5, 20, 35, 50}, true},
+		{"A step over a range", "10-40/10 * * * *", []int{10, 20, 30, 40}, true},
+		{"Too few fields", "0 9 * *", nil, false},
+		{"Out of range", "60 * * * *", nil, false},
+		{"Backwards range", "30-10 * * * *", nil, false},
+		{"Zero step", "*/0 * * * *", nil, false},
+		{"Not a number", "nine * * * *", nil, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := parseSchedule(c.spec)
+			if ok := err == nil; ok != c.ok {
+				t.Fatalf("expected ok to be %v, got error %v", c.ok, err)
+			}
+			if !c.ok || c.minutes == nil {
+				return
+			}
+
+			if len(s.minutes) != len(c.minutes) {
+				t.Errorf("expected minutes %v, got %v", c.minutes, s.minutes)
+			}
+			for _, minute := range c.minutes {
+				if !s.minutes[minute] {
+					t.Errorf("expected minutes %v, got %v", c.minutes, s.minutes)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	// 2019-01-07 was a Monday
+	monday := time.Date(2019, 1, 7, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"Weekday mornings on a Monday", "0 9 * * 1-5", monday, true},
+		{"Weekday mornings on a Sunday", "0 9 * * 1-5", monday.Add(-24 * time.Hour), false},
+		{"Weekday mornings at the wrong minute", "0 9 * * 1-5", monday.Add(time.Minute), false},
+		{"Times are in UTC", "0 9 * * *", monday.In(time.FixedZone("UTC+1", 60*60)), true},
+		{"A step from a value", "5/15 * * * *", monday.Add(20 * time.Minute), true},
+		{"Between the steps", "5/15 * * * *", monday.Add(15 * time.Minute), false},
+		{"Either the day of the month or the week", "0 9 1 * 1", monday, true},
+		{"Neither the day of the month nor the week", "0 9 1 * 2", monday, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := parseSchedule(c.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := s.matches(c.at); got != c.want {
+				t.Errorf("expected %q to match %s: %v, got %v", c.spec, c.at, c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const EnvVarPrefixForAwsRoles = "AWS_ROLE_"
+const DefaultAwsRegion = "us-east-1"
+
+// Account is an AWS account (and region) that slash-infra has been
+// configured to discover resources within
+type Account struct {
+	// Alias is the {account alias} part of `AWS_ROLE_{account alias}`
+	Alias string
+	// ID is the AWS account ID, taken from the role's ARN
+	ID      string
+	Region  string
+	RoleArn string
+
+	session *session.Session
+	config  *aws.Config
+}
+
+// AccountsFromEnvironment uses environment variables to work out which
+// AWS accounts slash-infra should discover resources within, and how it
+// should authenticate with them.
+//
+// The main variables for configuration are:
+//
+// `AWS_ROLE_{account alias}` - The role slash-infra should assume to gain access
+// to the account known as {account alias}
+//
+// `AWS_REGION_{account alias}` - If the account's resources are in a region
+// other than us-east-1, specify it here.
+//
+// If an account uses several regions, then you can specify role several times
+// under different aliases. e.g.
+//
+// ```
+// AWS_ROLE_DEV_US_EAST=...
+// AWS_ROLE_DEV_EU=...
+// ```
+func AccountsFromEnvironment() []Account {
+	accounts := []Account{}
+	environ := os.Environ()
+
+	for _, pair := range environ {
+		if !strings.HasPrefix(pair, EnvVarPrefixForAwsRoles) {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		key := parts[0]
+		roleArn := parts[1]
+
+		awsAccountAlias := key[len(EnvVarPrefixForAwsRoles):]
+
+		// Some of our infra is not in us-east-1 (e.g. dev-vpc)
+		// Allow slash-infra to create clients that will discover resources in those regions
+		region := os.Getenv(fmt.Sprintf("AWS_REGION_%s", awsAccountAlias))
+		if region == "" {
+			region = DefaultAwsRegion
+		}
+
+		sess := session.Must(session.NewSession(&aws.Config{
+			Credentials: credentials.NewEnvCredentials(),
+			// Setting here rather than in env variables as not all of our
+			// accounts are in us-east-1
+			Region: aws.String(region),
+		}))
+		creds := stscreds.NewCredentials(sess, roleArn)
+
+		accounts = append(accounts, Account{
+			Alias:   awsAccountAlias,
+			ID:      accountIDFromRoleArn(roleArn),
+			Region:  region,
+			RoleArn: roleArn,
+			session: sess,
+			config:  &aws.Config{Credentials: creds},
+		})
+	}
+
+	// os.Environ() makes no promises about ordering, sorting keeps the
+	// order results are displayed in stable
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Alias < accounts[j].Alias })
+
+	return accounts
+}
+
+// uniqueAccounts returns one entry per AWS account. Useful for services
+// like route53 which are global, and would otherwise be searched once for
+// every region an account is configured for
+func uniqueAccounts(accounts []Account) []Account {
+	seen := map[string]bool{}
+	unique := []Account{}
+
+	for _, account := range accounts {
+		key := account.ID
+		if key == "" {
+			key = account.RoleArn
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, account)
+	}
+
+	return unique
+}
+
+// accountIDFromRoleArn extracts the account ID from a role ARN in the
+// form arn:aws:iam::{account id}:role/{role name}
+func accountIDFromRoleArn(roleArn string) string {
+	parts := strings.SplitN(roleArn, ":", 6)
+	if len(parts) != 6 {
+		return ""
+	}
+
+	return parts[4]
+}
+
+// metadata describes where a resource was found, so that it can be
+// merged into a Result's metadata
+func (a Account) metadata() map[string][]string {
+	return map[string][]string{
+		"account":    []string{a.Alias},
+		"account_id": []string{a.ID},
+		"region":     []string{a.Region},
+	}
+}
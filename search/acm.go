@@ -0,0 +1,301 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/acm"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// DefaultCertificateExpiryWindowDays is used by `certs expiring` when no
+// window is given
+const DefaultCertificateExpiryWindowDays = 30
+
+// certificateExpiryReportPattern matches `certs expiring` reports, with an
+// optional window in days, e.g. `certs expiring 30d`
+var certificateExpiryReportPattern = regexp.MustCompile(`^(?i)certs expiring(?:\s+(\d+)d)?$`)
+
+// Certificates have to be described one at a time, so a few are described
+// at once
+const acmDescribeConcurrency = 5
+
+type acmSDK interface {
+	ListCertificatesPagesWithContext(ctx aws.Context, input *acm.ListCertificatesInput, fn func(*acm.ListCertificatesOutput, bool) bool, opts ...request.Option) error
+	DescribeCertificateWithContext(ctx aws.Context, input *acm.DescribeCertificateInput, opts ...request.Option) (*acm.DescribeCertificateOutput, error)
+}
+
+type acmClient struct {
+	acmSDK
+	account Account
+}
+
+func buildAcmClients(accounts []Account) []acmClient {
+	clients := []acmClient{}
+
+	for _, account := range accounts {
+		svc := acm.New(account.session, account.config)
+
+		clients = append(clients, acmClient{acmSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+func NewAcm(accounts []Account) *ACMResolver {
+	return &ACMResolver{clients: buildAcmClients(accounts)}
+}
+
+// ACMResolver finds the certificates that cover a domain, e.g.
+// `api.example.com` or `cert:*.example.com`, and reports on certificates
+// that are about to expire, e.g. `certs expiring 30d`
+type ACMResolver struct {
+	clients []acmClient
+}
+
+func (a *ACMResolver) Search(ctx context.Context, query string) []ResultSet {
+	if match := certificateExpiryReportPattern.FindStringSubmatch(query); match != nil {
+		days := DefaultCertificateExpiryWindowDays
+		if match[1] != "" {
+			days, _ = strconv.Atoi(match[1])
+		}
+
+		return []ResultSet{a.expiryReport(ctx, time.Duration(days)*24*time.Hour)}
+	}
+
+	results := []ResultSet{}
+
+	domain, ok := trimQueryPrefix(query, "cert:")
+	domain = normaliseHostname(domain)
+	if !ok && !isHostname(domain) {
+		return results
+	}
+
+	for _, client := range a.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		result, err := findCertificatesByDomain(ctx, client, domain)
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+// expiryReport lists every issued certificate in every account that
+// expires within window, soonest first
+func (a *ACMResolver) expiryReport(ctx context.Context, window time.Duration) ResultSet {
+	deadline := time.Now().Add(window)
+	expiring := []*acm.CertificateDetail{}
+	accounts := map[*acm.CertificateDetail]Account{}
+
+	// Several aliases can share an account and region, and would list the
+	// same certificates
+	listed := map[string]bool{}
+	seen := map[string]bool{}
+
+	for _, client := range a.clients {
+		key := client.account.ID + "/" + client.account.Region
+		if listed[key] {
+			continue
+		}
+		listed[key] = true
+
+		certificates, err := listCertificates(ctx, client, []string{acm.CertificateStatusIssued}, func(certificate *acm.CertificateDetail) bool {
+			return certificate.NotAfter != nil && certificate.NotAfter.Before(deadline)
+		})
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+
+		for _, certificate := range certificates {
+			arn := aws.StringValue(certificate.CertificateArn)
+			if seen[arn] {
+				continue
+			}
+			seen[arn] = true
+
+			expiring = append(expiring, certificate)
+			accounts[certificate] = client.account
+		}
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].NotAfter.Before(*expiring[j].NotAfter)
+	})
+
+	results := []Result{}
+	for _, certificate := range expiring {
+		results = append(results, certificateToResult(accounts[certificate], certificate))
+	}
+
+	return ResultSet{Kind: "acm.expiry_report", Results: results}
+}
+
+func findCertificatesByDomain(ctx context.Context, client acmClient, domain string) (*ResultSet, error) {
+	statuses := []string{
+		acm.CertificateStatusIssued,
+		acm.CertificateStatusPendingValidation,
+		acm.CertificateStatusInactive,
+		acm.CertificateStatusExpired,
+		acm.CertificateStatusFailed,
+	}
+
+	certificates, err := listCertificates(ctx, client, statuses, func(certificate *acm.CertificateDetail) bool {
+		names := append([]*string{certificate.DomainName}, certificate.SubjectAlternativeNames...)
+		for _, name := range names {
+			if certificateNameCovers(aws.StringValue(name), domain) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certificates) == 0 {
+		return nil, nil
+	}
+
+	results := []Result{}
+	for _, certificate := range certificates {
+		results = append(results, certificateToResult(client.account, certificate))
+	}
+
+	return &ResultSet{Kind: "acm.certificate", Results: results}, nil
+}
+
+// listCertificates describes every certificate with one of statuses that
+// match is true for. Certificates only list their alternative names and
+// expiry date when described, so every one of them has to be.
+func listCertificates(ctx context.Context, client acmClient, statuses []string, match func(*acm.CertificateDetail) bool) ([]*acm.CertificateDetail, error) {
+	arns := []*string{}
+
+	err := client.ListCertificatesPagesWithContext(
+		ctx,
+		&acm.ListCertificatesInput{CertificateStatuses: aws.StringSlice(statuses)},
+		func(page *acm.ListCertificatesOutput, lastPage bool) bool {
+			for _, summary := range page.CertificateSummaryList {
+				arns = append(arns, summary.CertificateArn)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	described := make([]*acm.CertificateDetail, len(arns))
+	errs := make([]error, len(arns))
+	limit := make(chan struct{}, acmDescribeConcurrency)
+	wg := sync.WaitGroup{}
+
+	for i, arn := range arns {
+		wg.Add(1)
+		limit <- struct{}{}
+
+		go func(i int, arn *string) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+
+			output, err := client.DescribeCertificateWithContext(ctx, &acm.DescribeCertificateInput{CertificateArn: arn})
+			if err != nil {
+				// The certificate may have been deleted since it was listed
+				if awsErrorCode(err) != acm.ErrCodeResourceNotFoundException {
+					errs[i] = err
+				}
+				return
+			}
+
+			described[i] = output.Certificate
+		}(i, arn)
+	}
+	wg.Wait()
+
+	certificates := []*acm.CertificateDetail{}
+	for i, certificate := range described {
+		if errs[i] != nil {
+			bugsnag.Notify(errs[i])
+			return nil, errs[i]
+		}
+
+		if certificate != nil && match(certificate) {
+			certificates = append(certificates, certificate)
+		}
+	}
+
+	return certificates, nil
+}
+
+// certificateNameCovers is true if a certificate issued for name can be
+// used for domain. Wildcards only cover a single label, so
+// `*.example.com` covers `api.example.com` but not `example.com` or
+// `eu.api.example.com`.
+func certificateNameCovers(name, domain string) bool {
+	name = normaliseHostname(name)
+
+	if name == domain {
+		return true
+	}
+
+	if !strings.HasPrefix(name, "*.") {
+		return false
+	}
+
+	labels := strings.SplitN(domain, ".", 2)
+	return len(labels) == 2 && labels[0] != "*" && labels[1] == name[2:]
+}
+
+func certificateToResult(account Account, certificate *acm.CertificateDetail) Result {
+	arn := aws.StringValue(certificate.CertificateArn)
+
+	result := newResult("acm.certificate", account)
+	result.Metadata["certificate_arn"] = []string{arn}
+	result.Metadata["domain_name"] = []string{aws.StringValue(certificate.DomainName)}
+	result.Metadata["subject_alternative_names"] = aws.StringValueSlice(certificate.SubjectAlternativeNames)
+	result.Metadata["status"] = []string{aws.StringValue(certificate.Status)}
+	result.Metadata["type"] = []string{aws.StringValue(certificate.Type)}
+	result.Metadata["renewal_eligibility"] = []string{aws.StringValue(certificate.RenewalEligibility)}
+	result.Metadata["in_use_by"] = aws.StringValueSlice(certificate.InUseBy)
+
+	if certificate.NotAfter != nil {
+		result.Metadata["expires_at"] = []string{certificate.NotAfter.UTC().Format("2006-01-02 15:04:05 MST")}
+		result.Metadata["days_until_expiry"] = []string{strconv.Itoa(int(time.Until(*certificate.NotAfter).Hours() / 24))}
+	}
+	if certificate.RenewalSummary != nil {
+		result.Metadata["renewal_status"] = []string{aws.StringValue(certificate.RenewalSummary.RenewalStatus)}
+	}
+
+	result.Links["acm_console"] = acmConsoleLink(account.Region, arn)
+
+	return result
+}
+
+func acmConsoleLink(region, arn string) string {
+	return fmt.Sprintf(
+		"https://console.aws.amazon.com/acm/home?region=%s#/certificates/%s",
+		region,
+		arn[strings.LastIndex(arn, "/")+1:],
+	)
+}
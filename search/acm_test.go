@@ -0,0 +1,27 @@
+package search
+
+import "testing"
+
+func TestCertificateNameCovers(t *testing.T) {
+	cases := []struct {
+		name   string
+		domain string
+		want   bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"API.example.com.", "api.example.com", true},
+		{"*.example.com", "api.example.com", true},
+		{`\052.example.com`, "api.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "v2.api.example.com", false},
+		{"*.example.com", "*.example.com", true},
+		{"*.example.com", "api.example.org", false},
+		{"api.example.com", "www.example.com", false},
+	}
+
+	for _, c := range cases {
+		if got := certificateNameCovers(c.name, c.domain); got != c.want {
+			t.Errorf("expected %q covering %q to be %v, got %v", c.name, c.domain, c.want, got)
+		}
+	}
+}
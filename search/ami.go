@@ -0,0 +1,168 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// This is 17 characters plus the "ami-" prefix
+const ExactAmiIDLength = 21
+
+func isAmiID(query string) bool {
+	return strings.HasPrefix(query, "ami-") && len(query) == ExactAmiIDLength
+}
+
+// findImageUsage describes an AMI, and counts how many running instances
+// in each account were launched from it. Images are often shared between
+// accounts, so unlike other EC2 resources this produces one result for
+// all accounts.
+//
+// Instances may still be using an image that has since been deregistered,
+// in which case the result only has the usage counts.
+func (e *EC2Resolver) findImageUsage(ctx context.Context, imageID string) *ResultSet {
+	var image *ec2.Image
+	var imageAccount Account
+
+	usage := []string{}
+	totalRunning := 0
+
+	for _, client := range e.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		found, err := describeImage(ctx, client, imageID)
+		if err != nil {
+			log.Print(err)
+		}
+		// Prefer the account that owns the image, as it can see
+		// everything about it
+		if found != nil && (image == nil || aws.StringValue(found.OwnerId) == client.account.ID) {
+			image = found
+			imageAccount = client.account
+		}
+
+		running, err := countRunningInstancesUsingImage(ctx, client, imageID)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if running > 0 {
+			usage = append(usage, fmt.Sprintf("%s (%s): %d", client.account.Alias, client.account.Region, running))
+			totalRunning += running
+		}
+	}
+
+	if image == nil && totalRunning == 0 {
+		return nil
+	}
+
+	result := Result{
+		Kind:     "ec2.image",
+		Metadata: map[string][]string{"image_id": []string{imageID}},
+		Links:    map[string]string{},
+	}
+	if image != nil {
+		result = imageToResult(imageAccount, image)
+	}
+
+	result.Metadata["running_instances"] = usage
+	result.Metadata["total_running_instances"] = []string{strconv.Itoa(totalRunning)}
+
+	return &ResultSet{Kind: "ec2.image", Results: []Result{result}}
+}
+
+func describeImage(ctx context.Context, client ec2Client, imageID string) (*ec2.Image, error) {
+	// Filtering, rather than using ImageIds, means images we can't see
+	// give us an empty response instead of an error
+	output, err := client.DescribeImagesWithContext(
+		ctx,
+		&ec2.DescribeImagesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{Name: aws.String("image-id"), Values: []*string{aws.String(imageID)}},
+			},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(output.Images) == 0 {
+		return nil, nil
+	}
+
+	return output.Images[0], nil
+}
+
+func countRunningInstancesUsingImage(ctx context.Context, client ec2Client, imageID string) (int, error) {
+	count := 0
+
+	err := client.DescribeInstancesPagesWithContext(
+		ctx,
+		&ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{Name: aws.String("image-id"), Values: []*string{aws.String(imageID)}},
+				&ec2.Filter{Name: aws.String("instance-state-name"), Values: []*string{aws.String(ec2.InstanceStateNameRunning)}},
+			},
+		},
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				count += len(reservation.Instances)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func imageToResult(account Account, image *ec2.Image) Result {
+	imageID := aws.StringValue(image.ImageId)
+
+	snapshotIDs := []string{}
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs != nil && mapping.Ebs.SnapshotId != nil {
+			snapshotIDs = append(snapshotIDs, *mapping.Ebs.SnapshotId)
+		}
+	}
+
+	result := newResult("ec2.image", account)
+	result.Metadata["image_id"] = []string{imageID}
+	result.Metadata["name"] = []string{aws.StringValue(image.Name)}
+	result.Metadata["description"] = []string{aws.StringValue(image.Description)}
+	result.Metadata["state"] = []string{aws.StringValue(image.State)}
+	result.Metadata["created_at"] = []string{aws.StringValue(image.CreationDate)}
+	result.Metadata["owner_id"] = []string{aws.StringValue(image.OwnerId)}
+	result.Metadata["snapshot_ids"] = snapshotIDs
+
+	if image.ImageOwnerAlias != nil {
+		result.Metadata["owner_alias"] = []string{*image.ImageOwnerAlias}
+	}
+	if image.DeprecationTime != nil {
+		result.Metadata["deprecation_time"] = []string{*image.DeprecationTime}
+	}
+
+	for _, tag := range image.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+	}
+
+	result.Links["ec2_console"] = imageConsoleLink(account.Region, imageID)
+
+	return result
+}
+
+func imageConsoleLink(region, imageID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ec2/v2/home?region=%s#Images:search=%s", region, imageID)
+}
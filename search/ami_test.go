@@ -0,0 +1,59 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestImageToResult(t *testing.T) {
+	account := Account{Alias: "production", ID: "123456789012", Region: "eu-west-2"}
+
+	cases := []struct {
+		name  string
+		image *ec2.Image
+		want  map[string][]string
+	}{
+		{
+			name: "Deprecated image",
+			image: &ec2.Image{
+				ImageId:         aws.String("ami-0123456789abcdef0"),
+				Name:            aws.String("base-2019-01-01"),
+				DeprecationTime: aws.String("2021-07-01T00:00:00.000Z"),
+				BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+					{Ebs: &ec2.EbsBlockDevice{SnapshotId: aws.String("snap-0123456789abcdef0")}},
+					{VirtualName: aws.String("ephemeral0")},
+				},
+			},
+			want: map[string][]string{
+				"deprecation_time": {"2021-07-01T00:00:00.000Z"},
+				"snapshot_ids":     {"snap-0123456789abcdef0"},
+			},
+		},
+		{
+			name: "Image without a deprecation time",
+			image: &ec2.Image{
+				ImageId: aws.String("ami-0123456789abcdef0"),
+				Name:    aws.String("base-2019-01-01"),
+			},
+			want: map[string][]string{
+				"deprecation_time": nil,
+				"snapshot_ids":     {},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := imageToResult(account, c.image)
+
+			for key, want := range c.want {
+				if got := result.Metadata[key]; !reflect.DeepEqual(got, want) {
+					t.Errorf("expected %s to be %q, got %q", key, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// arn is a parsed Amazon Resource Name, e.g.
+//
+//	arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0
+//
+// Global resources, like S3 buckets, have no region or account ID
+type arn struct {
+	raw       string
+	partition string
+	service   string
+	region    string
+	accountID string
+	resource  string
+}
+
+func parseArn(query string) (arn, bool) {
+	parts := strings.SplitN(query, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] == "" || parts[5] == "" {
+		return arn{}, false
+	}
+
+	return arn{
+		raw:       query,
+		partition: parts[1],
+		service:   parts[2],
+		region:    parts[3],
+		accountID: parts[4],
+		resource:  parts[5],
+	}, true
+}
+
+// query turns the ARN into a query the resolver for its service
+// understands. Resolvers that already understand ARNs, like lambda and
+// SQS, are given the ARN as-is.
+func (a arn) query() string {
+	resourceType, id := a.resourceTypeAndID()
+
+	switch a.service {
+	case "ec2":
+		// e.g. instance/i-0123..., volume/vol-0123... or image/ami-0123...
+		return id
+	case "dynamodb":
+		// Streams and indexes live under the table, e.g. table/name/stream/...
+		return "ddb:" + strings.SplitN(id, "/", 2)[0]
+	case "autoscaling":
+		// e.g. autoScalingGroup:uuid:autoScalingGroupName/name
+		return "asg:" + a.resource[strings.LastIndex(a.resource, "/")+1:]
+	case "cloudformation":
+		// e.g. stack/name/uuid
+		return "stack:" + strings.SplitN(id, "/", 2)[0]
+	case "ecs":
+		if resourceType == "service" {
+			// e.g. service/cluster/name, or service/name for older ARNs
+			return "svc:" + a.resource[strings.LastIndex(a.resource, "/")+1:]
+		}
+	}
+
+	return a.raw
+}
+
+// resourceTypeAndID splits resources like `instance/i-0123...` or
+// `function:name`
+func (a arn) resourceTypeAndID() (string, string) {
+	i := strings.IndexAny(a.resource, "/:")
+	if i == -1 {
+		return "", a.resource
+	}
+
+	return a.resource[:i], a.resource[i+1:]
+}
+
+// unknownAccountResultSet explains that an ARN belongs to an account, or a
+// region of an account, that slash-infra hasn't been given a role for
+func unknownAccountResultSet(a arn, accounts []Account) ResultSet {
+	visible := []string{}
+	for _, account := range uniqueAccounts(accounts) {
+		regions := []string{}
+		for _, other := range accounts {
+			if other.ID == account.ID && !containsString(regions, other.Region) {
+				regions = append(regions, other.Region)
+			}
+		}
+
+		visible = append(visible, fmt.Sprintf("%s (%s) in %s", account.Alias, account.ID, strings.Join(regions, ", ")))
+	}
+
+	// The account is known, but not in the ARN's region
+	knownAccount := false
+	for _, account := range accounts {
+		if account.ID == a.accountID {
+			knownAccount = true
+		}
+	}
+
+	return ResultSet{
+		Kind: "arn.unknown_account",
+		Results: []Result{
+			Result{
+				Kind: "arn.unknown_account",
+				Metadata: map[string][]string{
+					"arn":              []string{a.raw},
+					"service":          []string{a.service},
+					"region":           []string{a.region},
+					"account_id":       []string{a.accountID},
+					"known_account":    []string{strconv.FormatBool(knownAccount)},
+					"visible_accounts": visible,
+				},
+				Links: map[string]string{},
+			},
+		},
+	}
+}
+
+type accountScopeKey struct{}
+
+// accountScope limits a search to one account and region, e.g. the ones
+// named in an ARN. An empty region matches every region.
+type accountScope struct {
+	accountID string
+	region    string
+}
+
+func scopeToAccount(ctx context.Context, accountID, region string) context.Context {
+	return context.WithValue(ctx, accountScopeKey{}, accountScope{accountID: accountID, region: region})
+}
+
+// inScope is true if resolvers should search account. Searches are only
+// scoped when the query says which account to look in.
+func inScope(ctx context.Context, account Account) bool {
+	scope, ok := ctx.Value(accountScopeKey{}).(accountScope)
+	if !ok {
+		return true
+	}
+
+	if scope.accountID != "" && scope.accountID != account.ID {
+		return false
+	}
+
+	return scope.region == "" || scope.region == account.Region
+}
@@ -0,0 +1,178 @@
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseArn(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		ok    bool
+		want  arn
+	}{
+		{
+			name:  "EC2 instances have an account and region",
+			query: "arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0",
+			ok:    true,
+			want: arn{
+				partition: "aws",
+				service:   "ec2",
+				region:    "eu-west-2",
+				accountID: "123456789012",
+				resource:  "instance/i-0123456789abcdef0",
+			},
+		},
+		{
+			name:  "S3 buckets have no account or region",
+			query: "arn:aws:s3:::my-bucket",
+			ok:    true,
+			want:  arn{partition: "aws", service: "s3", resource: "my-bucket"},
+		},
+		{
+			name:  "AMIs have no account",
+			query: "arn:aws:ec2:us-east-1::image/ami-0123456789abcdef0",
+			ok:    true,
+			want: arn{
+				partition: "aws",
+				service:   "ec2",
+				region:    "us-east-1",
+				resource:  "image/ami-0123456789abcdef0",
+			},
+		},
+		{
+			name:  "Resources can contain colons",
+			query: "arn:aws:lambda:eu-west-2:123456789012:function:payments-webhook:3",
+			ok:    true,
+			want: arn{
+				partition: "aws",
+				service:   "lambda",
+				region:    "eu-west-2",
+				accountID: "123456789012",
+				resource:  "function:payments-webhook:3",
+			},
+		},
+		{
+			name:  "Other partitions are understood",
+			query: "arn:aws-cn:sqs:cn-north-1:123456789012:queue",
+			ok:    true,
+			want: arn{
+				partition: "aws-cn",
+				service:   "sqs",
+				region:    "cn-north-1",
+				accountID: "123456789012",
+				resource:  "queue",
+			},
+		},
+		{name: "Too few parts", query: "arn:aws:s3:my-bucket"},
+		{name: "No service", query: "arn:aws::eu-west-2:123456789012:thing"},
+		{name: "No resource", query: "arn:aws:ec2:eu-west-2:123456789012:"},
+		{name: "Not an ARN", query: "i-0123456789abcdef0"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := parseArn(c.query)
+			if ok != c.ok {
+				t.Fatalf("expected ok to be %v, got %v", c.ok, ok)
+			}
+			if !ok {
+				return
+			}
+
+			c.want.raw = c.query
+			if got != c.want {
+				t.Errorf("expected %+v, got %+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestArnQuery(t *testing.T) {
+	cases := map[string]string{
+		"arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0":                           "i-0123456789abcdef0",
+		"arn:aws:dynamodb:eu-west-2:123456789012:table/ledger/stream/2019-01-01T00:00:00.000":       "ddb:ledger",
+		"arn:aws:ecs:eu-west-2:123456789012:service/production/payments-api":                        "svc:payments-api",
+		"arn:aws:cloudformation:eu-west-2:123456789012:stack/payments/0123-4567":                    "stack:payments",
+		"arn:aws:lambda:eu-west-2:123456789012:function:payments-webhook":                           "arn:aws:lambda:eu-west-2:123456789012:function:payments-webhook",
+		"arn:aws:autoscaling:eu-west-2:123456789012:autoScalingGroup:0123:autoScalingGroupName/web": "asg:web",
+	}
+
+	for query, want := range cases {
+		parsed, ok := parseArn(query)
+		if !ok {
+			t.Errorf("could not parse %s", query)
+			continue
+		}
+
+		if got := parsed.query(); got != want {
+			t.Errorf("expected %s to be searched for as %q, got %q", query, want, got)
+		}
+	}
+}
+
+func TestInScope(t *testing.T) {
+	account := Account{ID: "123456789012", Region: "eu-west-2"}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"Searches aren't scoped by default", context.Background(), true},
+		{"Same account and region", scopeToAccount(context.Background(), "123456789012", "eu-west-2"), true},
+		{"Any region", scopeToAccount(context.Background(), "123456789012", ""), true},
+		{"Any account", scopeToAccount(context.Background(), "", "eu-west-2"), true},
+		{"Another account", scopeToAccount(context.Background(), "210987654321", "eu-west-2"), false},
+		{"Another region", scopeToAccount(context.Background(), "123456789012", "us-east-1"), false},
+	}
+
+	for _, c := range cases {
+		if got := inScope(c.ctx, account); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestSearchArnsOutsideConfiguredAccounts(t *testing.T) {
+	accounts := []Account{
+		{Alias: "production", ID: "123456789012", Region: "eu-west-2"},
+		{Alias: "production", ID: "123456789012", Region: "us-east-1"},
+	}
+	searcher := NewSearcher(accounts)
+
+	cases := []struct {
+		name         string
+		query        string
+		unknown      bool
+		knownAccount string
+	}{
+		{"Configured account and region", "arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0", false, ""},
+		{"Configured account in another region", "arn:aws:ec2:ap-southeast-2:123456789012:instance/i-0123456789abcdef0", true, "true"},
+		{"Unknown account", "arn:aws:ec2:eu-west-2:210987654321:instance/i-0123456789abcdef0", true, "false"},
+		{"Global resources", "arn:aws:s3:::my-bucket", false, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			results := searcher.Search(context.Background(), c.query)
+
+			unknown := len(results) == 1 && results[0].Kind == "arn.unknown_account"
+			if unknown != c.unknown {
+				t.Fatalf("expected the ARN to be outside the configured accounts: %v, got %v", c.unknown, unknown)
+			}
+			if !unknown {
+				return
+			}
+
+			result := results[0].Results[0]
+			if got := result.GetMetadata("known_account"); got != c.knownAccount {
+				t.Errorf("expected known_account to be %q, got %q", c.knownAccount, got)
+			}
+			if got := result.GetMetadata("visible_accounts"); got != "production (123456789012) in eu-west-2, us-east-1" {
+				t.Errorf("expected the configured regions to be listed, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// AutoScalingActivitiesToShow is how many of a group's most recent scaling
+// activities are included in its result
+const AutoScalingActivitiesToShow = 5
+
+// AutoScalingGroupTag is added by AWS to every instance launched by an
+// auto scaling group
+const AutoScalingGroupTag = "aws:autoscaling:groupName"
+
+type autoscalingSDK interface {
+	DescribeAutoScalingGroupsWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, opts ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+	DescribeAutoScalingInstancesWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingInstancesInput, opts ...request.Option) (*autoscaling.DescribeAutoScalingInstancesOutput, error)
+	DescribeScalingActivitiesWithContext(ctx aws.Context, input *autoscaling.DescribeScalingActivitiesInput, opts ...request.Option) (*autoscaling.DescribeScalingActivitiesOutput, error)
+}
+
+type autoscalingClient struct {
+	autoscalingSDK
+	account Account
+}
+
+func buildAutoscalingClients(accounts []Account) []autoscalingClient {
+	clients := []autoscalingClient{}
+
+	for _, account := range accounts {
+		svc := autoscaling.New(account.session, account.config)
+
+		clients = append(clients, autoscalingClient{autoscalingSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+func NewAutoscaling(accounts []Account) *AutoscalingResolver {
+	return &AutoscalingResolver{clients: buildAutoscalingClients(accounts)}
+}
+
+// AutoscalingResolver finds auto scaling groups either by name, prefixed
+// with `asg:`, or by the ID of an instance in the group. Names need the
+// prefix so that every other query, like a partial instance ID, doesn't
+// call DescribeAutoScalingGroups in every account.
+type AutoscalingResolver struct {
+	clients []autoscalingClient
+}
+
+func (a *AutoscalingResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	for _, client := range a.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		var result *ResultSet
+		var err error
+
+		if isEc2InstanceID(query) {
+			result, err = findAutoScalingGroupsByInstanceID(ctx, client, query)
+		} else if name, ok := trimQueryPrefix(query, "asg:"); ok {
+			result, err = findAutoScalingGroupsByName(ctx, client, name)
+		}
+
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func findAutoScalingGroupsByInstanceID(ctx context.Context, client autoscalingClient, instanceID string) (*ResultSet, error) {
+	output, err := client.DescribeAutoScalingInstancesWithContext(
+		ctx,
+		&autoscaling.DescribeAutoScalingInstancesInput{
+			InstanceIds: []*string{aws.String(instanceID)},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(output.AutoScalingInstances) == 0 {
+		return nil, nil
+	}
+
+	return findAutoScalingGroupsByName(ctx, client, aws.StringValue(output.AutoScalingInstances[0].AutoScalingGroupName))
+}
+
+func findAutoScalingGroupsByName(ctx context.Context, client autoscalingClient, name string) (*ResultSet, error) {
+	output, err := client.DescribeAutoScalingGroupsWithContext(
+		ctx,
+		&autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: []*string{aws.String(name)},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(output.AutoScalingGroups) == 0 {
+		return nil, nil
+	}
+
+	results := []Result{}
+
+	for _, group := range output.AutoScalingGroups {
+		result := autoScalingGroupToResult(client.account, group)
+
+		activities, err := client.DescribeScalingActivitiesWithContext(
+			ctx,
+			&autoscaling.DescribeScalingActivitiesInput{
+				AutoScalingGroupName: group.AutoScalingGroupName,
+				MaxRecords:           aws.Int64(AutoScalingActivitiesToShow),
+			},
+		)
+		if err != nil {
+			bugsnag.Notify(err)
+			return nil, err
+		}
+
+		recentActivities := []string{}
+		for _, activity := range activities.Activities {
+			recentActivities = append(recentActivities, fmt.Sprintf(
+				"%s `%s` %s",
+				aws.TimeValue(activity.StartTime).Format("2006-01-02 15:04"),
+				aws.StringValue(activity.StatusCode),
+				aws.StringValue(activity.Description),
+			))
+		}
+		result.Metadata["recent_activities"] = recentActivities
+
+		results = append(results, result)
+	}
+
+	return &ResultSet{Kind: "autoscaling.group", Results: results}, nil
+}
+
+func autoScalingGroupToResult(account Account, group *autoscaling.Group) Result {
+	name := aws.StringValue(group.AutoScalingGroupName)
+
+	instanceIDs := []string{}
+	instances := []string{}
+	for _, instance := range group.Instances {
+		instanceIDs = append(instanceIDs, aws.StringValue(instance.InstanceId))
+		instances = append(instances, fmt.Sprintf(
+			"%s (%s, %s)",
+			aws.StringValue(instance.InstanceId),
+			aws.StringValue(instance.LifecycleState),
+			aws.StringValue(instance.HealthStatus),
+		))
+	}
+
+	result := newResult("autoscaling.group", account)
+	result.Metadata["name"] = []string{name}
+	result.Metadata["desired_capacity"] = []string{strconv.FormatInt(aws.Int64Value(group.DesiredCapacity), 10)}
+	result.Metadata["min_size"] = []string{strconv.FormatInt(aws.Int64Value(group.MinSize), 10)}
+	result.Metadata["max_size"] = []string{strconv.FormatInt(aws.Int64Value(group.MaxSize), 10)}
+	result.Metadata["instance_ids"] = instanceIDs
+	result.Metadata["instances"] = instances
+
+	// Groups either use a launch template directly, a launch template
+	// as part of a mixed instances policy, or a legacy launch configuration
+	launchTemplate := group.LaunchTemplate
+	if launchTemplate == nil && group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil {
+		launchTemplate = group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	if launchTemplate != nil {
+		templateName := aws.StringValue(launchTemplate.LaunchTemplateName)
+		if templateName == "" {
+			templateName = aws.StringValue(launchTemplate.LaunchTemplateId)
+		}
+		result.Metadata["launch_template"] = []string{templateName}
+		result.Metadata["launch_template_version"] = []string{aws.StringValue(launchTemplate.Version)}
+	}
+	if group.LaunchConfigurationName != nil {
+		result.Metadata["launch_configuration"] = []string{*group.LaunchConfigurationName}
+	}
+
+	result.Links["autoscaling_console"] = autoScalingGroupConsoleLink(account.Region, name)
+
+	return result
+}
+
+func autoScalingGroupConsoleLink(region, name string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ec2/autoscaling/home?region=%s#AutoScalingGroups:id=%s;view=details", region, name)
+}
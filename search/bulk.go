@@ -0,0 +1,281 @@
+package search
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// BulkLookupMaxIdentifiers caps how many identifiers are looked up from a
+// pasted blob of text
+const BulkLookupMaxIdentifiers = 50
+
+// bulkSearchConcurrency caps how many identifiers that batch resolvers
+// didn't find are searched for at once
+const bulkSearchConcurrency = 10
+
+// ec2FilterValuesLimit is the most values the EC2 API accepts in a filter
+const ec2FilterValuesLimit = 200
+
+// BatchResolver can look up many queries at once, e.g. with one API call
+// per account rather than one per query. Queries it can't find anything
+// for are left out of the map.
+type BatchResolver interface {
+	SearchBatch(ctx context.Context, queries []string) map[string][]Result
+}
+
+// extractIdentifiers pulls every instance ID, IP, ARN and hostname out of
+// free text, like log lines or an alert body, in the order they first
+// appear
+func extractIdentifiers(text string) []string {
+	tokens := strings.FieldsFunc(text, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && !strings.ContainsRune("-_.:/*", c)
+	})
+
+	seen := map[string]bool{}
+	identifiers := []string{}
+
+	for _, token := range tokens {
+		token = strings.Trim(token, ".:/")
+
+		// Ports and log prefixes, e.g. 10.1.2.3:8080 or host=ip-10-1-2-3
+		if _, ok := parseArn(token); !ok && strings.Contains(token, ":") {
+			token = strings.SplitN(token, ":", 2)[0]
+		}
+
+		if seen[token] || !isBulkIdentifier(token) {
+			continue
+		}
+
+		seen[token] = true
+		identifiers = append(identifiers, token)
+
+		if len(identifiers) == BulkLookupMaxIdentifiers {
+			break
+		}
+	}
+
+	return identifiers
+}
+
+// isBulkIdentifier is stricter about hostnames than single queries, as
+// free text is full of things like `main.go` or `e.g.`
+func isBulkIdentifier(token string) bool {
+	if isEc2InstanceID(token) || isIPv4Address(token) {
+		return true
+	}
+
+	if _, ok := parseArn(token); ok {
+		return true
+	}
+
+	if _, _, ok := parseEc2PrivateHostname(token); ok && strings.HasPrefix(token, "ip-") {
+		return true
+	}
+
+	return isHostname(token) && strings.Count(token, ".") >= 2
+}
+
+// bulkSearch looks up every identifier in a blob of text. Resolvers that
+// can batch lookups are asked first, and whatever they don't find is
+// searched for one identifier at a time.
+//
+// The results are flattened into a single "bulk.lookup" result set, with
+// each result's `token` metadata saying which identifier found it.
+// Identifiers that weren't found have a "bulk.not_found" result.
+func (s *Searcher) bulkSearch(ctx context.Context, identifiers []string) []ResultSet {
+	found := map[string][]Result{}
+
+	for _, resolver := range s.resolvers {
+		if batch, ok := resolver.(BatchResolver); ok {
+			for token, results := range batch.SearchBatch(ctx, identifiers) {
+				found[token] = append(found[token], results...)
+			}
+		}
+	}
+
+	// Batched results haven't been through the annotators, unlike those
+	// searched for one at a time
+	batched := []ResultSet{}
+	for _, token := range identifiers {
+		if len(found[token]) > 0 {
+			batched = append(batched, ResultSet{Kind: "bulk.batch", Results: found[token]})
+		}
+	}
+	s.annotate(ctx, batched)
+
+	// Work out what's left before starting any searches, so that found
+	// isn't read while they're writing to it
+	unresolved := []string{}
+	for _, token := range identifiers {
+		if len(found[token]) == 0 {
+			unresolved = append(unresolved, token)
+		}
+	}
+
+	searched := make([][]Result, len(unresolved))
+	var (
+		wg    sync.WaitGroup
+		limit = make(chan struct{}, bulkSearchConcurrency)
+	)
+	for i, token := range unresolved {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, token string) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+
+			searched[i] = flattenResultSets(s.search(ctx, token))
+		}(i, token)
+	}
+	wg.Wait()
+
+	for i, token := range unresolved {
+		found[token] = searched[i]
+	}
+
+	rows := []Result{}
+	for _, token := range identifiers {
+		if len(found[token]) == 0 {
+			rows = append(rows, Result{
+				Kind:     "bulk.not_found",
+				Metadata: map[string][]string{"token": []string{token}},
+				Links:    map[string]string{},
+			})
+			continue
+		}
+
+		// The same result can be found by several tokens, e.g. an instance's
+		// ID and its IP, so each row gets its own copy of the metadata
+		for _, result := range found[token] {
+			row := result.copy()
+			row.Metadata["token"] = []string{token}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return []ResultSet{
+		{Kind: "bulk.lookup", Results: rows},
+	}
+}
+
+// flattenResultSets turns the result sets for a query into a list of the
+// resources that were found. DNS chains only contribute the resources at
+// the end of the chain, not the records or hostnames that led to them.
+func flattenResultSets(sets []ResultSet) []Result {
+	results := []Result{}
+
+	for _, set := range sets {
+		for _, result := range set.Results {
+			if set.Kind == "route53.chain" && (result.Kind == "route53.record" || result.Kind == "dns.unresolved") {
+				continue
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// SearchBatch finds the instances for every instance ID and IP in queries.
+// Accounts that have been crawled are served from the inventory; anything
+// it doesn't know about is left for the single searches, which fall back
+// to the EC2 API. Other accounts are asked for all of the instance IDs in
+// one call, and all of the IPs in another (filters in a single call must
+// all match, so IDs, private IPs and public IPs can't share a call).
+func (e *EC2Resolver) SearchBatch(ctx context.Context, queries []string) map[string][]Result {
+	instanceIDs := []string{}
+	ips := []string{}
+	for _, query := range queries {
+		if isEc2InstanceID(query) {
+			instanceIDs = append(instanceIDs, query)
+		} else if isIPv4Address(query) {
+			ips = append(ips, query)
+		}
+	}
+
+	found := map[string][]Result{}
+	// An instance can be returned by more than one batch, e.g. if both its
+	// private and public IPs were pasted
+	seen := map[string]bool{}
+
+	for _, client := range e.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		if snapshot := e.inventory.snapshot(client.account); snapshot != nil {
+			for _, query := range queries {
+				if results := snapshot.find(query); len(results) > 0 {
+					found[query] = append(found[query], results...)
+				}
+			}
+			continue
+		}
+
+		batches := []*ec2.Filter{}
+		for _, values := range chunkStrings(instanceIDs, ec2FilterValuesLimit) {
+			batches = append(batches, &ec2.Filter{Name: aws.String("instance-id"), Values: aws.StringSlice(values)})
+		}
+		for _, values := range chunkStrings(ips, ec2FilterValuesLimit) {
+			batches = append(batches,
+				&ec2.Filter{Name: aws.String("private-ip-address"), Values: aws.StringSlice(values)},
+				&ec2.Filter{Name: aws.String("ip-address"), Values: aws.StringSlice(values)},
+			)
+		}
+
+		for _, filter := range batches {
+			results, err := describeEC2Instances(ctx, client, filter)
+			if err != nil {
+				log.Print(err)
+				continue
+			}
+
+			for _, result := range results {
+				for _, query := range queries {
+					matches := result.GetMetadata("instance_id") == query || containsString(result.Metadata["private_ips"], query) || containsString(result.Metadata["public_ips"], query)
+
+					key := query + "/" + result.GetMetadata("instance_id")
+					if matches && !seen[key] {
+						seen[key] = true
+						found[query] = append(found[query], result)
+					}
+				}
+			}
+		}
+	}
+
+	return found
+}
+
+func chunkStrings(values []string, size int) [][]string {
+	chunks := [][]string{}
+
+	for len(values) > size {
+		chunks = append(chunks, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+
+	return chunks
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractIdentifiers(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "Log lines",
+			text: "2019-01-02T03:04:05Z ERROR connection to 10.1.2.3:5432 from i-0123456789abcdef0 timed out",
+			want: []string{"10.1.2.3", "i-0123456789abcdef0"},
+		},
+		{
+			name: "Identifiers are only listed once",
+			text: "i-0123456789abcdef0 restarted, i-0123456789abcdef0 healthy",
+			want: []string{"i-0123456789abcdef0"},
+		},
+		{
+			name: "ARNs keep their colons",
+			text: "denied: arn:aws:lambda:eu-west-2:123456789012:function:payments-webhook, retrying",
+			want: []string{"arn:aws:lambda:eu-west-2:123456789012:function:payments-webhook"},
+		},
+		{
+			name: "Private hostnames and log prefixes",
+			text: "host=ip-10-1-2-3.eu-west-2.compute.internal status=down",
+			want: []string{"ip-10-1-2-3.eu-west-2.compute.internal"},
+		},
+		{
+			name: "Hostnames need at least three labels",
+			text: "see main.go, e.g. api.example.com.",
+			want: []string{"api.example.com"},
+		},
+		{
+			name: "Nothing to look up",
+			text: "the deploy failed again",
+			want: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := extractIdentifiers(c.text); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expected %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestExtractIdentifiersIsCapped(t *testing.T) {
+	ips := []string{}
+	for i := 0; i < BulkLookupMaxIdentifiers+10; i++ {
+		ips = append(ips, fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+
+	got := extractIdentifiers(strings.Join(ips, "\n"))
+	if !reflect.DeepEqual(got, ips[:BulkLookupMaxIdentifiers]) {
+		t.Errorf("expected the first %d IPs, got %q", BulkLookupMaxIdentifiers, got)
+	}
+}
+
+// fakeResolver finds a result for each query it has, and can batch them
+type fakeResolver struct {
+	found   map[string]string
+	batched map[string]string
+}
+
+func (f fakeResolver) Search(ctx context.Context, query string) []ResultSet {
+	id, ok := f.found[query]
+	if !ok {
+		return []ResultSet{}
+	}
+
+	return []ResultSet{{Kind: "ec2.instance", Results: []Result{testInstance(id, nil)}}}
+}
+
+func (f fakeResolver) SearchBatch(ctx context.Context, queries []string) map[string][]Result {
+	results := map[string][]Result{}
+	for _, query := range queries {
+		if id, ok := f.batched[query]; ok {
+			results[query] = []Result{testInstance(id, nil)}
+		}
+	}
+
+	return results
+}
+
+func TestBulkSearch(t *testing.T) {
+	resolver := fakeResolver{
+		found:   map[string]string{},
+		batched: map[string]string{"10.0.0.1": "i-batched"},
+	}
+	identifiers := []string{"10.0.0.1"}
+	want := map[string]string{"10.0.0.1": "i-batched"}
+
+	// More unresolved tokens than can be searched at once
+	for i := 2; i < 2+bulkSearchConcurrency*3; i++ {
+		token := fmt.Sprintf("10.0.0.%d", i)
+		identifiers = append(identifiers, token)
+		if i%2 == 0 {
+			resolver.found[token] = fmt.Sprintf("i-%d", i)
+			want[token] = fmt.Sprintf("i-%d", i)
+		} else {
+			want[token] = ""
+		}
+	}
+
+	sets := NewSearcher([]Account{}, resolver).bulkSearch(context.Background(), identifiers)
+	if len(sets) != 1 || sets[0].Kind != "bulk.lookup" {
+		t.Fatalf("expected a single bulk.lookup result set, got %v", sets)
+	}
+
+	got := map[string]string{}
+	for _, row := range sets[0].Results {
+		got[row.GetMetadata("token")] = row.GetMetadata("instance_id")
+		if row.Kind == "bulk.not_found" && row.GetMetadata("instance_id") != "" {
+			t.Errorf("expected rows for tokens that weren't found to be empty, got %v", row.Metadata)
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
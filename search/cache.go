@@ -0,0 +1,167 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long search results are reused for when no TTL is
+// configured. It's short, as it's only meant to stop everyone in an
+// incident channel searching AWS for the same thing at once.
+const DefaultCacheTTL = 30 * time.Second
+
+// FreshFlag can be added to a query to skip the cache, e.g. to see whether
+// an instance has finished stopping
+const FreshFlag = "--fresh"
+
+// SharedSearchTimeout stops a search that hangs, e.g. on an AWS API that's
+// having problems, from holding up every search that's waiting for it
+const SharedSearchTimeout = time.Minute
+
+type cacheEntry struct {
+	sets      []ResultSet
+	expiresAt time.Time
+}
+
+// inflightSearch is a search that's still running, which searches for the
+// same query can wait for rather than calling AWS themselves
+type inflightSearch struct {
+	done chan struct{}
+	sets []ResultSet
+}
+
+// resultCache keeps the result sets for recent searches, and makes
+// identical searches that run at the same time share one set of AWS calls
+type resultCache struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*inflightSearch
+}
+
+func newResultCache(ttl time.Duration) *resultCache {
+	return &resultCache{
+		ttl:      ttl,
+		entries:  map[string]cacheEntry{},
+		inflight: map[string]*inflightSearch{},
+	}
+}
+
+// get returns the cached result sets for key, or runs search to find them.
+// Fresh searches ignore the cache and searches that are already running,
+// but still update the cache for everyone else.
+func (c *resultCache) get(ctx context.Context, key string, fresh bool, search func(context.Context) []ResultSet) []ResultSet {
+	c.mu.Lock()
+	if !fresh {
+		if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expiresAt) {
+			c.mu.Unlock()
+			return entry.sets
+		}
+
+		if call, ok := c.inflight[key]; ok {
+			c.mu.Unlock()
+			return call.wait(ctx)
+		}
+	}
+
+	call := &inflightSearch{done: make(chan struct{})}
+	if !fresh {
+		c.inflight[key] = call
+	}
+	c.mu.Unlock()
+
+	searchCtx, cancel := context.WithTimeout(ctx, SharedSearchTimeout)
+	defer cancel()
+
+	call.sets = search(searchCtx)
+	close(call.done)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.inflight[key] == call {
+		delete(c.inflight, key)
+	}
+	c.prune()
+	// Searches that were cut short may be missing results
+	if searchCtx.Err() == nil {
+		c.entries[key] = cacheEntry{sets: call.sets, expiresAt: time.Now().Add(c.ttl)}
+	}
+
+	return call.sets
+}
+
+// prune forgets expired entries, so that the cache doesn't grow forever.
+// The caller must hold c.mu.
+func (c *resultCache) prune() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// wait returns the results of a search that's already running, or nothing
+// if ctx is cancelled first
+func (s *inflightSearch) wait(ctx context.Context) []ResultSet {
+	select {
+	case <-s.done:
+		return s.sets
+	case <-ctx.Done():
+		return []ResultSet{}
+	}
+}
+
+// cacheKey identifies a search by its query, ignoring whitespace, and by
+// the account and region it's limited to. That's the caller's scope if it
+// has one, e.g. for a stack's resources, or otherwise the one named by an
+// ARN or private hostname in the query, so that the same resource written
+// in different ways shares results. Only hostnames ignore case, as names
+// and tags in AWS are case sensitive, e.g. `stack:Payments` and
+// `stack:payments` are different stacks.
+func cacheKey(ctx context.Context, query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if isHostname(query) {
+		query = normaliseHostname(query)
+	}
+
+	scope, ok := ctx.Value(accountScopeKey{}).(accountScope)
+	if !ok {
+		if arn, isArn := parseArn(query); isArn {
+			scope = accountScope{accountID: arn.accountID, region: arn.region}
+			query = arn.query()
+		} else if target, region, isHostname := parseEc2PrivateHostname(query); isHostname {
+			scope = accountScope{region: region}
+			query = target
+		}
+	}
+
+	return fmt.Sprintf("%s/%s/%s", scope.accountID, scope.region, query)
+}
+
+// trimFreshFlag removes FreshFlag from a query, and says whether it was
+// there
+func trimFreshFlag(query string) (string, bool) {
+	fields := strings.Fields(query)
+	kept := []string{}
+	fresh := false
+
+	for _, field := range fields {
+		if field == FreshFlag {
+			fresh = true
+			continue
+		}
+		kept = append(kept, field)
+	}
+
+	if !fresh {
+		return query, false
+	}
+
+	return strings.Join(kept, " "), true
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCacheKey(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"Whitespace is ignored", "asg:web  production", " asg:web production ", true},
+		{"Case is ignored in hostnames", "API.example.com", "api.example.com", true},
+		{"Case matters in stack names", "stack:Payments", "stack:payments", false},
+		{"Case matters in function names", "fn:Payments-Webhook", "fn:payments-webhook", false},
+		{"Case matters in group names", "asg:Web", "asg:web", false},
+		{"Case matters in tag values", "tag:Role=Web", "tag:Role=web", false},
+		{"ARNs share results with the same resource in the same account", "arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0", "arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0 ", true},
+		{"ARNs don't share results with unscoped searches", "arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0", "i-0123456789abcdef0", false},
+		{"ARNs in different accounts", "arn:aws:ec2:eu-west-2:123456789012:instance/i-0123456789abcdef0", "arn:aws:ec2:eu-west-2:210987654321:instance/i-0123456789abcdef0", false},
+		{"Private hostnames are scoped to their region", "ip-10-1-2-3.eu-west-2.compute.internal", "10.1.2.3", false},
+		{"Private hostnames in the same region", "ip-10-1-2-3.eu-west-2.compute.internal", "ip-10-1-2-3.eu-west-2.compute.internal.", true},
+	}
+
+	for _, c := range cases {
+		if same := cacheKey(ctx, c.a) == cacheKey(ctx, c.b); same != c.same {
+			t.Errorf("%s: expected the keys for %q and %q to be the same: %v", c.name, c.a, c.b, c.same)
+		}
+	}
+
+	scoped := scopeToAccount(ctx, "123456789012", "eu-west-2")
+	if cacheKey(scoped, "asg:web") == cacheKey(ctx, "asg:web") {
+		t.Error("expected searches scoped by the caller not to share results with unscoped ones")
+	}
+}
@@ -0,0 +1,288 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// DefaultChangesWindow is used by `changes` when no window is given
+const DefaultChangesWindow = 24 * time.Hour
+
+// changesReportPattern matches `changes` reports, with an optional window
+// and account in either order, e.g. `changes 24h account:production`,
+// `changes account:production 7d` or `changes`
+var changesReportPattern = regexp.MustCompile(`^(?i)changes((?:\s+(?:\d+[smhd]|account:\S+))*)$`)
+
+// Kinds of change to an instance
+const (
+	ChangeLaunched       = "launched"
+	ChangeTerminated     = "terminated"
+	ChangeInstanceType   = "type_changed"
+	ChangeSecurityGroups = "security_groups_changed"
+	ChangeTags           = "tags_changed"
+)
+
+// ChangeKinds lists the kinds of change in the order they're reported
+var ChangeKinds = []string{ChangeLaunched, ChangeTerminated, ChangeInstanceType, ChangeSecurityGroups, ChangeTags}
+
+func NewChanges(store *SnapshotStore) *ChangesResolver {
+	return &ChangesResolver{store: store}
+}
+
+// ChangesResolver compares inventory snapshots to find out what changed
+// recently, e.g. `changes 24h account:production` lists the instances that
+// were launched, terminated, resized, or had their security groups or tags
+// changed in the last day
+type ChangesResolver struct {
+	store *SnapshotStore
+}
+
+func (c *ChangesResolver) ReportKeyword() string {
+	return "changes"
+}
+
+func (c *ChangesResolver) Search(ctx context.Context, query string) []ResultSet {
+	window, account, ok := parseChangesQuery(query)
+	if !ok {
+		return []ResultSet{}
+	}
+
+	return []ResultSet{c.report(ctx, time.Now().Add(-window), account)}
+}
+
+// parseChangesQuery returns the window and account of a `changes` report
+func parseChangesQuery(query string) (time.Duration, string, bool) {
+	match := changesReportPattern.FindStringSubmatch(query)
+	if match == nil {
+		return 0, "", false
+	}
+
+	window, account := DefaultChangesWindow, ""
+	for _, option := range strings.Fields(match[1]) {
+		// Account aliases keep their case
+		if strings.HasPrefix(strings.ToLower(option), "account:") {
+			account = option[len("account:"):]
+		} else {
+			window = parseChangesWindow(strings.ToLower(option))
+		}
+	}
+
+	return window, account, true
+}
+
+// report diffs each account's snapshot from since with its latest one. If
+// there are no snapshots that old, the oldest one is used instead.
+func (c *ChangesResolver) report(ctx context.Context, since time.Time, account string) ResultSet {
+	changes := []Result{}
+
+	aliases, err := c.store.Aliases()
+	if err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+		return ResultSet{Kind: "inventory.changes", Results: changes}
+	}
+
+	for _, alias := range aliases {
+		before, after, err := c.snapshotsSince(alias, since)
+		if err != nil {
+			bugsnag.Notify(err)
+			log.Print(err)
+			continue
+		}
+
+		if after == nil || !inScope(ctx, Account{ID: after.AccountID, Region: after.Region}) {
+			continue
+		}
+
+		if account != "" && account != after.Alias && account != after.AccountID {
+			continue
+		}
+
+		changes = append(changes, diffSnapshots(before, after)...)
+	}
+
+	rank := func(change Result) int {
+		for i, kind := range ChangeKinds {
+			if change.GetMetadata("change") == kind {
+				return i
+			}
+		}
+		return len(ChangeKinds)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return rank(changes[i]) < rank(changes[j])
+	})
+
+	return ResultSet{Kind: "inventory.changes", Results: changes}
+}
+
+func (c *ChangesResolver) snapshotsSince(alias string, since time.Time) (*InventorySnapshot, *InventorySnapshot, error) {
+	before, err := c.store.At(alias, since)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if before == nil {
+		if before, err = c.store.Oldest(alias); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	after, err := c.store.At(alias, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return before, after, nil
+}
+
+// diffSnapshots lists what changed about instances between two snapshots
+// of the same account. Terminated instances drop out of DescribeInstances
+// after about an hour, so instances that are missing from the later
+// snapshot count as terminated too.
+func diffSnapshots(before, after *InventorySnapshot) []Result {
+	changes := []Result{}
+	if before == nil || after == nil || before.TakenAt.Equal(after.TakenAt) {
+		return changes
+	}
+
+	change := func(instance Result, kind string, details ...string) {
+		result := instance.copy()
+		result.Kind = "inventory.change"
+		result.Metadata["change"] = []string{kind}
+		result.Metadata["details"] = details
+		result.Metadata["since"] = []string{before.TakenAt.Format(time.RFC3339)}
+		result.Metadata["until"] = []string{after.TakenAt.Format(time.RFC3339)}
+
+		changes = append(changes, result)
+	}
+
+	for _, instance := range after.Resources {
+		id := instance.GetMetadata("instance_id")
+		previous, existed := before.byID[id]
+
+		switch {
+		case !existed || isTerminated(previous):
+			if !isTerminated(instance) {
+				change(instance, ChangeLaunched, instance.GetMetadata("instance_type"))
+			}
+		case isTerminated(instance):
+			change(instance, ChangeTerminated)
+		default:
+			// Snapshots taken before a detail was recorded don't say what it
+			// was, which isn't the same as it being empty
+			if from, to := previous.GetMetadata("instance_type"), instance.GetMetadata("instance_type"); recorded("instance_type", previous, instance) && from != to {
+				change(instance, ChangeInstanceType, fmt.Sprintf("%s → %s", from, to))
+			}
+			if details := diffLists(previous.Metadata["security_groups"], instance.Metadata["security_groups"]); recorded("security_groups", previous, instance) && len(details) > 0 {
+				change(instance, ChangeSecurityGroups, details...)
+			}
+			if details := diffTags(previous, instance); len(details) > 0 {
+				change(instance, ChangeTags, details...)
+			}
+		}
+	}
+
+	for _, instance := range before.Resources {
+		if _, exists := after.byID[instance.GetMetadata("instance_id")]; !exists && !isTerminated(instance) {
+			change(instance, ChangeTerminated)
+		}
+	}
+
+	return changes
+}
+
+// recorded is true if every one of instances has the metadata key, even if
+// it's empty
+func recorded(key string, instances ...Result) bool {
+	for _, instance := range instances {
+		if _, ok := instance.Metadata[key]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTerminated(instance Result) bool {
+	return instance.GetMetadata("instance_state") == "terminated"
+}
+
+// diffLists says which values were added (+) and removed (-)
+func diffLists(before, after []string) []string {
+	details := []string{}
+
+	for _, value := range after {
+		if !containsString(before, value) {
+			details = append(details, "+"+value)
+		}
+	}
+	for _, value := range before {
+		if !containsString(after, value) {
+			details = append(details, "-"+value)
+		}
+	}
+
+	return details
+}
+
+// diffTags says which tags were added, removed or changed, e.g.
+// `Role: web → db`
+func diffTags(before, after Result) []string {
+	keys := map[string]bool{}
+	for key := range before.Metadata {
+		keys[key] = true
+	}
+	for key := range after.Metadata {
+		keys[key] = true
+	}
+
+	sorted := []string{}
+	for key := range keys {
+		if strings.HasPrefix(key, "tag:") {
+			sorted = append(sorted, key)
+		}
+	}
+	sort.Strings(sorted)
+
+	details := []string{}
+	for _, key := range sorted {
+		from, hadTag := before.Metadata[key]
+		to, hasTag := after.Metadata[key]
+		name := strings.TrimPrefix(key, "tag:")
+
+		switch {
+		case !hadTag:
+			details = append(details, fmt.Sprintf("+%s=%s", name, strings.Join(to, ",")))
+		case !hasTag:
+			details = append(details, fmt.Sprintf("-%s=%s", name, strings.Join(from, ",")))
+		case strings.Join(from, ",") != strings.Join(to, ","):
+			details = append(details, fmt.Sprintf("%s: %s → %s", name, strings.Join(from, ","), strings.Join(to, ",")))
+		}
+	}
+
+	return details
+}
+
+// parseChangesWindow understands durations like `90m` or `24h`, and days,
+// which Go's durations don't, e.g. `7d`
+func parseChangesWindow(setting string) time.Duration {
+	if days, err := strconv.Atoi(strings.TrimSuffix(setting, "d")); err == nil && strings.HasSuffix(setting, "d") {
+		return time.Duration(days) * 24 * time.Hour
+	}
+
+	window, err := time.ParseDuration(setting)
+	if err != nil {
+		return DefaultChangesWindow
+	}
+
+	return window
+}
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testInstance(id string, metadata map[string][]string) Result {
+	result := Result{
+		Kind:     "ec2.instance",
+		Metadata: map[string][]string{"instance_id": []string{id}, "instance_state": []string{"running"}},
+		Links:    map[string]string{},
+	}
+	for key, values := range metadata {
+		result.Metadata[key] = values
+	}
+
+	return result
+}
+
+func testSnapshot(takenAt time.Time, instances ...Result) *InventorySnapshot {
+	snapshot := &InventorySnapshot{Alias: "production", TakenAt: takenAt, Resources: instances}
+	snapshot.index()
+
+	return snapshot
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	since := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+
+	cases := []struct {
+		name   string
+		before Result
+		after  Result
+		want   map[string][]string
+	}{
+		{
+			name:   "Nothing changed",
+			before: testInstance("i-1", map[string][]string{"instance_type": {"t3.small"}, "security_groups": {"sg-1 (web)"}}),
+			after:  testInstance("i-1", map[string][]string{"instance_type": {"t3.small"}, "security_groups": {"sg-1 (web)"}}),
+			want:   map[string][]string{},
+		},
+		{
+			name:   "Resized",
+			before: testInstance("i-1", map[string][]string{"instance_type": {"t3.small"}}),
+			after:  testInstance("i-1", map[string][]string{"instance_type": {"t3.large"}}),
+			want:   map[string][]string{ChangeInstanceType: {"t3.small → t3.large"}},
+		},
+		{
+			name:   "Security groups changed",
+			before: testInstance("i-1", map[string][]string{"security_groups": {"sg-1 (web)"}}),
+			after:  testInstance("i-1", map[string][]string{"security_groups": {"sg-2 (db)"}}),
+			want:   map[string][]string{ChangeSecurityGroups: {"+sg-2 (db)", "-sg-1 (web)"}},
+		},
+		{
+			name:   "Snapshots from before security groups were recorded",
+			before: testInstance("i-1", nil),
+			after:  testInstance("i-1", map[string][]string{"security_groups": {"sg-1 (web)"}}),
+			want:   map[string][]string{},
+		},
+		{
+			name:   "Tags changed",
+			before: testInstance("i-1", map[string][]string{"tag:Role": {"web"}, "tag:Old": {"yes"}}),
+			after:  testInstance("i-1", map[string][]string{"tag:Role": {"db"}, "tag:New": {"yes"}}),
+			want:   map[string][]string{ChangeTags: {"+New=yes", "-Old=yes", "Role: web → db"}},
+		},
+		{
+			name:   "Terminated",
+			before: testInstance("i-1", nil),
+			after:  testInstance("i-1", map[string][]string{"instance_state": {"terminated"}}),
+			want:   map[string][]string{ChangeTerminated: nil},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			changes := diffSnapshots(testSnapshot(since, c.before), testSnapshot(until, c.after))
+
+			got := map[string][]string{}
+			for _, change := range changes {
+				got[change.GetMetadata("change")] = change.Metadata["details"]
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expected %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshotsLaunchesAndDisappearances(t *testing.T) {
+	since := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := testSnapshot(since, testInstance("i-gone", nil))
+	after := testSnapshot(since.Add(time.Hour), testInstance("i-new", map[string][]string{"instance_type": {"t3.small"}}))
+
+	got := map[string]string{}
+	for _, change := range diffSnapshots(before, after) {
+		got[change.GetMetadata("instance_id")] = change.GetMetadata("change")
+	}
+
+	want := map[string]string{"i-new": ChangeLaunched, "i-gone": ChangeTerminated}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if changes := diffSnapshots(before, before); len(changes) != 0 {
+		t.Errorf("expected a snapshot compared with itself to have no changes, got %d", len(changes))
+	}
+}
+
+func TestParseChangesQuery(t *testing.T) {
+	cases := []struct {
+		query   string
+		window  time.Duration
+		account string
+		ok      bool
+	}{
+		{"changes", DefaultChangesWindow, "", true},
+		{"changes 7d", 7 * 24 * time.Hour, "", true},
+		{"changes 90m account:production", 90 * time.Minute, "production", true},
+		{"changes account:Production 12h", 12 * time.Hour, "Production", true},
+		{"Changes 24H", 24 * time.Hour, "", true},
+		{"changes account:123456789012", DefaultChangesWindow, "123456789012", true},
+		{"changes yesterday", 0, "", false},
+		{"changesets", 0, "", false},
+	}
+
+	for _, c := range cases {
+		window, account, ok := parseChangesQuery(c.query)
+		if ok != c.ok || window != c.window || account != c.account {
+			t.Errorf(
+				"expected %q to parse as (%s, %q, %v), got (%s, %q, %v)",
+				c.query, c.window, c.account, c.ok, window, account, ok,
+			)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// CloudFormationStackTag is added by CloudFormation to every resource it
+// creates that supports tagging
+const CloudFormationStackTag = "aws:cloudformation:stack-name"
+
+// CloudFormationResourcesToResolve caps how many of a stack's resources are
+// looked up through the other resolvers, so that big stacks don't fan out
+// into hundreds of API calls
+const CloudFormationResourcesToResolve = 10
+
+// stackResourceQueries turn the physical ID of a stack resource into a
+// query that another resolver understands
+var stackResourceQueries = map[string]func(string) string{
+	"AWS::EC2::Instance":                 func(id string) string { return id },
+	"AWS::EC2::Volume":                   func(id string) string { return id },
+	"AWS::EC2::EIP":                      func(id string) string { return id },
+	"AWS::Route53::RecordSet":            func(id string) string { return id },
+	"AWS::AutoScaling::AutoScalingGroup": func(id string) string { return "asg:" + id },
+	"AWS::Lambda::Function":              func(id string) string { return "fn:" + id },
+	"AWS::S3::Bucket":                    func(id string) string { return "s3://" + id },
+	"AWS::DynamoDB::Table":               func(id string) string { return "ddb:" + id },
+	// Queues' physical IDs are their URL, and topics' their ARN
+	"AWS::SQS::Queue":                    func(id string) string { return id },
+	"AWS::SNS::Topic":                    func(id string) string { return id },
+	"AWS::ElastiCache::CacheCluster":     func(id string) string { return "cache:" + id },
+	"AWS::ElastiCache::ReplicationGroup": func(id string) string { return "cache:" + id },
+	// Service ARNs look like arn:aws:ecs:region:account:service/cluster/name
+	"AWS::ECS::Service": func(id string) string { return "svc:" + id[strings.LastIndex(id, "/")+1:] },
+}
+
+type cloudFormationSDK interface {
+	DescribeStacksWithContext(ctx aws.Context, input *cloudformation.DescribeStacksInput, opts ...request.Option) (*cloudformation.DescribeStacksOutput, error)
+	ListStackResourcesPagesWithContext(ctx aws.Context, input *cloudformation.ListStackResourcesInput, fn func(*cloudformation.ListStackResourcesOutput, bool) bool, opts ...request.Option) error
+}
+
+type cloudFormationClient struct {
+	cloudFormationSDK
+	account Account
+}
+
+func buildCloudFormationClients(accounts []Account) []cloudFormationClient {
+	clients := []cloudFormationClient{}
+
+	for _, account := range accounts {
+		svc := cloudformation.New(account.session, account.config)
+
+		clients = append(clients, cloudFormationClient{cloudFormationSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+// NewCloudFormation creates a resolver for `stack:` queries. The resources
+// in a stack are looked up using resolvers.
+func NewCloudFormation(accounts []Account, resolvers ...Resolver) *CloudFormationResolver {
+	return &CloudFormationResolver{
+		clients:  buildCloudFormationClients(accounts),
+		searcher: NewSearcher(accounts, resolvers...),
+	}
+}
+
+// CloudFormationResolver lists the resources in a stack, e.g.
+// `stack:payments-production`. It also annotates results from other
+// resolvers with the stack they belong to.
+type CloudFormationResolver struct {
+	clients  []cloudFormationClient
+	searcher *Searcher
+}
+
+func (c *CloudFormationResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	name, ok := trimQueryPrefix(query, "stack:")
+	if !ok || name == "" {
+		return results
+	}
+
+	for _, client := range c.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		stack, resources, err := findStack(ctx, client, name)
+		if err != nil {
+			log.Print(err)
+		}
+
+		if stack == nil {
+			continue
+		}
+
+		results = append(results, ResultSet{Kind: "cloudformation.stack", Results: []Result{*stack}})
+		results = append(results, c.resolveStackResources(ctx, client.account, resources)...)
+	}
+
+	return results
+}
+
+// resolveStackResources looks up each resource through the resolver that
+// understands it, in the stack's account. Resources are looked up in
+// parallel, but returned in the order CloudFormation listed them.
+func (c *CloudFormationResolver) resolveStackResources(ctx context.Context, account Account, resources []*cloudformation.StackResourceSummary) []ResultSet {
+	queries := []string{}
+	for _, resource := range resources {
+		toQuery, ok := stackResourceQueries[aws.StringValue(resource.ResourceType)]
+		if !ok || resource.PhysicalResourceId == nil {
+			continue
+		}
+
+		queries = append(queries, toQuery(*resource.PhysicalResourceId))
+		if len(queries) == CloudFormationResourcesToResolve {
+			break
+		}
+	}
+
+	resultsByQuery := make([][]ResultSet, len(queries))
+
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			resultsByQuery[i] = c.searcher.Search(scopeToAccount(ctx, account.ID, account.Region), query)
+		}(i, query)
+	}
+	wg.Wait()
+
+	results := []ResultSet{}
+	for _, sets := range resultsByQuery {
+		results = append(results, sets...)
+	}
+
+	return results
+}
+
+func findStack(ctx context.Context, client cloudFormationClient, name string) (*Result, []*cloudformation.StackResourceSummary, error) {
+	stack, err := describeStack(ctx, client, name)
+	if err != nil || stack == nil {
+		return nil, nil, err
+	}
+
+	resources := []*cloudformation.StackResourceSummary{}
+	err = client.ListStackResourcesPagesWithContext(
+		ctx,
+		&cloudformation.ListStackResourcesInput{StackName: stack.StackId},
+		func(page *cloudformation.ListStackResourcesOutput, lastPage bool) bool {
+			resources = append(resources, page.StackResourceSummaries...)
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, nil, err
+	}
+
+	result := stackToResult(client.account, stack)
+	for _, resource := range resources {
+		result.Metadata["resources"] = append(result.Metadata["resources"], fmt.Sprintf(
+			"%s (%s): %s",
+			aws.StringValue(resource.LogicalResourceId),
+			aws.StringValue(resource.ResourceType),
+			aws.StringValue(resource.PhysicalResourceId),
+		))
+	}
+
+	return &result, resources, nil
+}
+
+// describeStack returns nil if the stack doesn't exist in the client's
+// account and region
+func describeStack(ctx context.Context, client cloudFormationClient, name string) (*cloudformation.Stack, error) {
+	output, err := client.DescribeStacksWithContext(
+		ctx,
+		&cloudformation.DescribeStacksInput{StackName: aws.String(name)},
+	)
+	if err != nil {
+		// CloudFormation doesn't have a "not found" error code
+		if awsErrorCode(err) == "ValidationError" && strings.Contains(err.Error(), "does not exist") {
+			return nil, nil
+		}
+
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(output.Stacks) == 0 {
+		return nil, nil
+	}
+
+	return output.Stacks[0], nil
+}
+
+func stackToResult(account Account, stack *cloudformation.Stack) Result {
+	updatedAt := stack.CreationTime
+	if stack.LastUpdatedTime != nil {
+		updatedAt = stack.LastUpdatedTime
+	}
+
+	result := newResult("cloudformation.stack", account)
+	result.Metadata["stack_name"] = []string{aws.StringValue(stack.StackName)}
+	result.Metadata["stack_id"] = []string{aws.StringValue(stack.StackId)}
+	result.Metadata["status"] = []string{aws.StringValue(stack.StackStatus)}
+	result.Metadata["updated_at"] = []string{aws.TimeValue(updatedAt).UTC().Format("2006-01-02 15:04:05 MST")}
+	result.Metadata["resources"] = []string{}
+
+	if stack.StackStatusReason != nil {
+		result.Metadata["status_reason"] = []string{*stack.StackStatusReason}
+	}
+	if stack.Description != nil {
+		result.Metadata["description"] = []string{*stack.Description}
+	}
+
+	for _, tag := range stack.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+	}
+
+	result.Links["cloudformation_console"] = cloudFormationConsoleLink(account.Region, aws.StringValue(stack.StackId))
+
+	return result
+}
+
+// Annotate adds the name, status, last update time and console link of
+// the CloudFormation stack that created each result, using the stack tag
+// CloudFormation adds to its resources
+func (c *CloudFormationResolver) Annotate(ctx context.Context, sets []ResultSet) {
+	type stackKey struct {
+		accountID, region, name string
+	}
+
+	byStack := map[stackKey][]Result{}
+	for _, set := range sets {
+		for _, result := range set.Results {
+			name := result.GetMetadata("tag:" + CloudFormationStackTag)
+			if name == "" {
+				continue
+			}
+
+			key := stackKey{result.GetMetadata("account_id"), result.GetMetadata("region"), name}
+			byStack[key] = append(byStack[key], result)
+		}
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for key, results := range byStack {
+		client, ok := c.clientFor(key.accountID, key.region)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(client cloudFormationClient, name string, results []Result) {
+			defer wg.Done()
+
+			stack, err := describeStack(ctx, client, name)
+			if err != nil {
+				log.Print(err)
+			}
+			if stack == nil {
+				return
+			}
+
+			summary := stackToResult(client.account, stack)
+
+			// Results share their metadata maps with the result sets
+			mu.Lock()
+			defer mu.Unlock()
+			for _, result := range results {
+				result.Metadata["cloudformation_stack"] = summary.Metadata["stack_name"]
+				result.Metadata["cloudformation_stack_status"] = summary.Metadata["status"]
+				result.Metadata["cloudformation_stack_updated_at"] = summary.Metadata["updated_at"]
+				result.Links["cloudformation_stack"] = summary.Links["cloudformation_console"]
+			}
+		}(client, key.name, results)
+	}
+	wg.Wait()
+}
+
+func (c *CloudFormationResolver) clientFor(accountID, region string) (cloudFormationClient, bool) {
+	for _, client := range c.clients {
+		if client.account.ID == accountID && client.account.Region == region {
+			return client, true
+		}
+	}
+
+	return cloudFormationClient{}, false
+}
+
+func cloudFormationConsoleLink(region, stackID string) string {
+	return fmt.Sprintf(
+		"https://console.aws.amazon.com/cloudformation/home?region=%s#/stacks/stackinfo?stackId=%s",
+		region,
+		url.QueryEscape(stackID),
+	)
+}
@@ -0,0 +1,189 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+type dynamoDBSDK interface {
+	DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error)
+	DescribeTimeToLiveWithContext(ctx aws.Context, input *dynamodb.DescribeTimeToLiveInput, opts ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error)
+	DescribeContinuousBackupsWithContext(ctx aws.Context, input *dynamodb.DescribeContinuousBackupsInput, opts ...request.Option) (*dynamodb.DescribeContinuousBackupsOutput, error)
+	ListTagsOfResourceWithContext(ctx aws.Context, input *dynamodb.ListTagsOfResourceInput, opts ...request.Option) (*dynamodb.ListTagsOfResourceOutput, error)
+	DescribeGlobalTableWithContext(ctx aws.Context, input *dynamodb.DescribeGlobalTableInput, opts ...request.Option) (*dynamodb.DescribeGlobalTableOutput, error)
+}
+
+type dynamoDBClient struct {
+	dynamoDBSDK
+	account Account
+}
+
+func buildDynamoDBClients(accounts []Account) []dynamoDBClient {
+	clients := []dynamoDBClient{}
+
+	for _, account := range accounts {
+		svc := dynamodb.New(account.session, account.config)
+
+		clients = append(clients, dynamoDBClient{dynamoDBSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+func NewDynamoDB(accounts []Account) *DynamoDBResolver {
+	return &DynamoDBResolver{clients: buildDynamoDBClients(accounts)}
+}
+
+// DynamoDBResolver finds tables by name, e.g. `ddb:payments-ledger` (the
+// `ddb:` prefix is optional)
+type DynamoDBResolver struct {
+	clients []dynamoDBClient
+}
+
+func (d *DynamoDBResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	name, ok := trimQueryPrefix(query, "ddb:")
+	if !ok && !isResourceName(query) {
+		return results
+	}
+
+	for _, client := range d.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		result, err := findDynamoDBTable(ctx, client, name)
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func findDynamoDBTable(ctx context.Context, client dynamoDBClient, name string) (*ResultSet, error) {
+	output, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if err != nil {
+		if awsErrorCode(err) == dynamodb.ErrCodeResourceNotFoundException {
+			return nil, nil
+		}
+
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	ttl, err := client.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(name)})
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	backups, err := client.DescribeContinuousBackupsWithContext(ctx, &dynamodb.DescribeContinuousBackupsInput{TableName: aws.String(name)})
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	tags, err := client.ListTagsOfResourceWithContext(ctx, &dynamodb.ListTagsOfResourceInput{ResourceArn: output.Table.TableArn})
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	// Only global tables have replicas
+	globalTable, err := client.DescribeGlobalTableWithContext(ctx, &dynamodb.DescribeGlobalTableInput{GlobalTableName: aws.String(name)})
+	if err != nil && awsErrorCode(err) != dynamodb.ErrCodeGlobalTableNotFoundException {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	result := tableToResult(client.account, output.Table)
+
+	if globalTable != nil && globalTable.GlobalTableDescription != nil {
+		status := aws.StringValue(globalTable.GlobalTableDescription.GlobalTableStatus)
+		for _, replica := range globalTable.GlobalTableDescription.ReplicationGroup {
+			result.Metadata["replicas"] = append(result.Metadata["replicas"], fmt.Sprintf(
+				"%s %s",
+				aws.StringValue(replica.RegionName),
+				status,
+			))
+		}
+	}
+
+	if description := ttl.TimeToLiveDescription; description != nil && aws.StringValue(description.TimeToLiveStatus) == dynamodb.TimeToLiveStatusEnabled {
+		result.Metadata["ttl_attribute"] = []string{aws.StringValue(description.AttributeName)}
+	}
+
+	if description := backups.ContinuousBackupsDescription; description != nil && description.PointInTimeRecoveryDescription != nil {
+		result.Metadata["point_in_time_recovery"] = []string{aws.StringValue(description.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus)}
+	}
+
+	for _, tag := range tags.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+	}
+
+	return &ResultSet{Kind: "dynamodb.table", Results: []Result{result}}, nil
+}
+
+func tableToResult(account Account, table *dynamodb.TableDescription) Result {
+	name := aws.StringValue(table.TableName)
+
+	// Tables created before on-demand billing existed have no summary
+	billingMode := dynamodb.BillingModeProvisioned
+	if table.BillingModeSummary != nil {
+		billingMode = aws.StringValue(table.BillingModeSummary.BillingMode)
+	}
+
+	result := newResult("dynamodb.table", account)
+	result.Metadata["table_name"] = []string{name}
+	result.Metadata["table_arn"] = []string{aws.StringValue(table.TableArn)}
+	result.Metadata["status"] = []string{aws.StringValue(table.TableStatus)}
+	result.Metadata["billing_mode"] = []string{billingMode}
+	result.Metadata["item_count"] = []string{strconv.FormatInt(aws.Int64Value(table.ItemCount), 10)}
+	result.Metadata["size_bytes"] = []string{strconv.FormatInt(aws.Int64Value(table.TableSizeBytes), 10)}
+	result.Metadata["global_secondary_indexes"] = []string{}
+	result.Metadata["replicas"] = []string{}
+
+	if billingMode == dynamodb.BillingModeProvisioned && table.ProvisionedThroughput != nil {
+		result.Metadata["read_capacity"] = []string{strconv.FormatInt(aws.Int64Value(table.ProvisionedThroughput.ReadCapacityUnits), 10)}
+		result.Metadata["write_capacity"] = []string{strconv.FormatInt(aws.Int64Value(table.ProvisionedThroughput.WriteCapacityUnits), 10)}
+	}
+
+	for _, index := range table.GlobalSecondaryIndexes {
+		description := fmt.Sprintf("%s %s", aws.StringValue(index.IndexName), aws.StringValue(index.IndexStatus))
+		if billingMode == dynamodb.BillingModeProvisioned && index.ProvisionedThroughput != nil {
+			description = fmt.Sprintf(
+				"%s (%d RCU, %d WCU)",
+				description,
+				aws.Int64Value(index.ProvisionedThroughput.ReadCapacityUnits),
+				aws.Int64Value(index.ProvisionedThroughput.WriteCapacityUnits),
+			)
+		}
+
+		result.Metadata["global_secondary_indexes"] = append(result.Metadata["global_secondary_indexes"], description)
+	}
+
+	if stream := table.StreamSpecification; stream != nil && aws.BoolValue(stream.StreamEnabled) {
+		result.Metadata["stream"] = []string{aws.StringValue(stream.StreamViewType)}
+	}
+
+	result.Links["dynamodb_console"] = dynamoDBConsoleLink(account.Region, name)
+
+	return result
+}
+
+func dynamoDBConsoleLink(region, name string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/dynamodb/home?region=%s#tables:selected=%s", region, name)
+}
@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// This is 17 characters plus the "vol-" prefix
+const ExactEbsVolumeIDLength = 21
+
+// This is 17 characters plus the "snap-" prefix
+const ExactEbsSnapshotIDLength = 22
+
+func findEbsVolumesByID(ctx context.Context, client ec2Client, search string) (*ResultSet, error) {
+	if !strings.HasPrefix(search, "vol-") || len(search) != ExactEbsVolumeIDLength {
+		return nil, nil
+	}
+
+	// Filtering, rather than using VolumeIds, means volumes in other
+	// accounts give us an empty response instead of an error
+	output, err := client.DescribeVolumesWithContext(
+		ctx,
+		&ec2.DescribeVolumesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{Name: aws.String("volume-id"), Values: []*string{aws.String(search)}},
+			},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, volume := range output.Volumes {
+		results = append(results, ebsVolumeToResult(client.account, volume))
+	}
+
+	return &ResultSet{Kind: "ebs.volume", Results: results}, nil
+}
+
+func ebsVolumeToResult(account Account, volume *ec2.Volume) Result {
+	volumeID := aws.StringValue(volume.VolumeId)
+
+	result := newResult("ebs.volume", account)
+	result.Metadata["volume_id"] = []string{volumeID}
+	result.Metadata["size_gib"] = []string{strconv.FormatInt(aws.Int64Value(volume.Size), 10)}
+	result.Metadata["volume_type"] = []string{aws.StringValue(volume.VolumeType)}
+	result.Metadata["state"] = []string{aws.StringValue(volume.State)}
+	result.Metadata["az"] = []string{aws.StringValue(volume.AvailabilityZone)}
+	result.Metadata["encrypted"] = []string{strconv.FormatBool(aws.BoolValue(volume.Encrypted))}
+	result.Metadata["created_at"] = []string{aws.TimeValue(volume.CreateTime).UTC().Format("2006-01-02 15:04:05 MST")}
+
+	if volume.Iops != nil {
+		result.Metadata["iops"] = []string{strconv.FormatInt(*volume.Iops, 10)}
+	}
+	if volume.KmsKeyId != nil {
+		result.Metadata["kms_key_id"] = []string{*volume.KmsKeyId}
+	}
+	if aws.StringValue(volume.SnapshotId) != "" {
+		result.Metadata["source_snapshot_id"] = []string{*volume.SnapshotId}
+		result.Links["source_snapshot"] = ebsSnapshotConsoleLink(account.Region, *volume.SnapshotId)
+	}
+
+	attachments := []string{}
+	for _, attachment := range volume.Attachments {
+		instanceID := aws.StringValue(attachment.InstanceId)
+
+		attachments = append(attachments, fmt.Sprintf(
+			"%s on %s (%s)",
+			instanceID,
+			aws.StringValue(attachment.Device),
+			aws.StringValue(attachment.State),
+		))
+		result.Metadata["instance_ids"] = append(result.Metadata["instance_ids"], instanceID)
+		result.Links["instance:"+instanceID] = ec2ConsoleLink(account.Region, instanceID)
+	}
+	result.Metadata["attachments"] = attachments
+
+	for _, tag := range volume.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+	}
+
+	result.Links["ec2_console"] = ebsVolumeConsoleLink(account.Region, volumeID)
+
+	return result
+}
+
+func findEbsSnapshotsByID(ctx context.Context, client ec2Client, search string) (*ResultSet, error) {
+	if !strings.HasPrefix(search, "snap-") || len(search) != ExactEbsSnapshotIDLength {
+		return nil, nil
+	}
+
+	output, err := client.DescribeSnapshotsWithContext(
+		ctx,
+		&ec2.DescribeSnapshotsInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{Name: aws.String("snapshot-id"), Values: []*string{aws.String(search)}},
+			},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, snapshot := range output.Snapshots {
+		results = append(results, ebsSnapshotToResult(client.account, snapshot))
+	}
+
+	return &ResultSet{Kind: "ebs.snapshot", Results: results}, nil
+}
+
+func ebsSnapshotToResult(account Account, snapshot *ec2.Snapshot) Result {
+	snapshotID := aws.StringValue(snapshot.SnapshotId)
+
+	result := newResult("ebs.snapshot", account)
+	result.Metadata["snapshot_id"] = []string{snapshotID}
+	result.Metadata["size_gib"] = []string{strconv.FormatInt(aws.Int64Value(snapshot.VolumeSize), 10)}
+	result.Metadata["state"] = []string{aws.StringValue(snapshot.State)}
+	result.Metadata["description"] = []string{aws.StringValue(snapshot.Description)}
+	result.Metadata["owner_id"] = []string{aws.StringValue(snapshot.OwnerId)}
+	result.Metadata["encrypted"] = []string{strconv.FormatBool(aws.BoolValue(snapshot.Encrypted))}
+	result.Metadata["created_at"] = []string{aws.TimeValue(snapshot.StartTime).UTC().Format("2006-01-02 15:04:05 MST")}
+
+	if snapshot.KmsKeyId != nil {
+		result.Metadata["kms_key_id"] = []string{*snapshot.KmsKeyId}
+	}
+	// Snapshots copied from another snapshot have a made up volume ID
+	if volumeID := aws.StringValue(snapshot.VolumeId); volumeID != "" && volumeID != "vol-ffffffff" {
+		result.Metadata["source_volume_id"] = []string{volumeID}
+		result.Links["source_volume"] = ebsVolumeConsoleLink(account.Region, volumeID)
+	}
+
+	for _, tag := range snapshot.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+	}
+
+	result.Links["ec2_console"] = ebsSnapshotConsoleLink(account.Region, snapshotID)
+
+	return result
+}
+
+func ebsVolumeConsoleLink(region, volumeID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ec2/v2/home?region=%s#Volumes:search=%s", region, volumeID)
+}
+
+func ebsSnapshotConsoleLink(region, snapshotID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ec2/v2/home?region=%s#Snapshots:search=%s", region, snapshotID)
+}
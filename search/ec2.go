@@ -4,117 +4,79 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"net"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	bugsnag "github.com/bugsnag/bugsnag-go"
 )
 
 // This is 17 characters plus the "i-" prefix
 const ExactEc2InstanceIDLength = 19
-const EnvVarPrefixForAwsRoles = "AWS_ROLE_"
 
 type ec2SDK interface {
 	DescribeInstancesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, opts ...request.Option) (*ec2.DescribeInstancesOutput, error)
+	DescribeInstancesPagesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, opts ...request.Option) error
+	DescribeInstanceStatusPagesWithContext(ctx aws.Context, input *ec2.DescribeInstanceStatusInput, fn func(*ec2.DescribeInstanceStatusOutput, bool) bool, opts ...request.Option) error
+	DescribeImagesWithContext(ctx aws.Context, input *ec2.DescribeImagesInput, opts ...request.Option) (*ec2.DescribeImagesOutput, error)
+	DescribeNetworkInterfacesWithContext(ctx aws.Context, input *ec2.DescribeNetworkInterfacesInput, opts ...request.Option) (*ec2.DescribeNetworkInterfacesOutput, error)
+	DescribeVolumesWithContext(ctx aws.Context, input *ec2.DescribeVolumesInput, opts ...request.Option) (*ec2.DescribeVolumesOutput, error)
+	DescribeVolumesPagesWithContext(ctx aws.Context, input *ec2.DescribeVolumesInput, fn func(*ec2.DescribeVolumesOutput, bool) bool, opts ...request.Option) error
+	DescribeSnapshotsWithContext(ctx aws.Context, input *ec2.DescribeSnapshotsInput, opts ...request.Option) (*ec2.DescribeSnapshotsOutput, error)
+	DescribeAddressesWithContext(ctx aws.Context, input *ec2.DescribeAddressesInput, opts ...request.Option) (*ec2.DescribeAddressesOutput, error)
+	DescribeNatGatewaysPagesWithContext(ctx aws.Context, input *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool, opts ...request.Option) error
 }
 
-// buildEc2ClientsFromEnvironment uses environment variables to build instances
-// of the EC2 client library for each AWS account it should discover resources
-// within.
-//
-// The main variables for configuration are:
-//
-// `AWS_ROLE_{account alias}` - The role slash-infra should assume to gain access
-// to the account known as {account alias}
-//
-// `AWS_REGION_{account alias}` - If the account's resources are in a region
-// other than us-east-1, specify it here.
-//
-// If an account uses several regions, then you can specify role several times
-// under different aliases. e.g.
-//
-// ```
-// AWS_ROLE_DEV_US_EAST=...
-// AWS_ROLE_DEV_EU=...
-// ```
-func buildEc2ClientsFromEnvironment() []ec2SDK {
-	clients := []ec2SDK{}
-	environ := os.Environ()
-
-	for _, pair := range environ {
-		if !strings.HasPrefix(pair, EnvVarPrefixForAwsRoles) {
-			continue
-		}
-		parts := strings.SplitN(pair, "=", 2)
-		key := parts[0]
-		roleArn := parts[1]
-
-		awsAccountAlias := key[len(EnvVarPrefixForAwsRoles):]
-
-		// Some of our infra is not in us-east-1 (e.g. dev-vpc)
-		// Allow slash-infra to create clients that will discover resources in those regions
-		region := os.Getenv(fmt.Sprintf("AWS_REGION_%s", awsAccountAlias))
-		if region == "" {
-			region = "us-east-1"
-		}
-
-		sess := session.Must(session.NewSession(&aws.Config{
-			Credentials: credentials.NewEnvCredentials(),
-			// Setting here rather than in env variables as not all of our
-			// accounts are in us-east-1
-			Region: aws.String(region),
-		}))
-		creds := stscreds.NewCredentials(sess, roleArn)
-		svc := ec2.New(sess, &aws.Config{Credentials: creds})
-
-		clients = append(clients, svc)
-	}
-
-	return clients
+// ec2InstanceFinders look for instances. They're only used if the instance
+// can't be found in the inventory.
+var ec2InstanceFinders = []func(context.Context, ec2Client, string) (*ResultSet, error){
+	findEC2InstancesByID,
+	findEC2InstancesByIP,
 }
 
-func NewEc2() *EC2Resolver {
-	return &EC2Resolver{clients: buildEc2ClientsFromEnvironment()}
+// ec2Finders look for resources that can be found using the EC2 API. Each
+// one should return quickly if it doesn't recognise the search
+var ec2Finders = []func(context.Context, ec2Client, string) (*ResultSet, error){
+	findEbsVolumesByID,
+	findEbsSnapshotsByID,
+	findElasticIPsByIP,
 }
 
-type Result struct {
-	Kind     string
-	Metadata map[string][]string
-	Links    map[string]string
+// ec2Client is an EC2 client that discovers resources in a specific
+// account and region
+type ec2Client struct {
+	ec2SDK
+	account Account
 }
 
-func (r Result) GetMetadata(key string) string {
-	set, ok := r.Metadata[key]
-	if !ok {
-		return ""
-	}
+// buildEc2Clients builds instances of the EC2 client library for each AWS
+// account it should discover resources within.
+func buildEc2Clients(accounts []Account) []ec2Client {
+	clients := []ec2Client{}
 
-	return strings.Join(set, ", ")
-}
+	for _, account := range accounts {
+		svc := ec2.New(account.session, account.config)
 
-func (r Result) GetLink(key string) string {
-	url, ok := r.Links[key]
-	if !ok {
-		return ""
+		clients = append(clients, ec2Client{ec2SDK: svc, account: account})
 	}
 
-	return url
+	return clients
 }
 
-type ResultSet struct {
-	Kind       string
-	SearchLink string
-	Results    []Result
+// NewEc2 creates a resolver for EC2 resources. Instances are looked up in
+// inventory before asking AWS, if an inventory is given.
+func NewEc2(accounts []Account, inventory *Inventory) *EC2Resolver {
+	return &EC2Resolver{clients: buildEc2Clients(accounts), inventory: inventory}
 }
 
 type EC2Resolver struct {
-	clients []ec2SDK
+	clients   []ec2Client
+	inventory *Inventory
 }
 
 func (e *EC2Resolver) Search(ctx context.Context, query string) []ResultSet {
@@ -123,7 +85,40 @@ func (e *EC2Resolver) Search(ctx context.Context, query string) []ResultSet {
 	query = strings.TrimSpace(query)
 
 	for _, client := range e.clients {
-		result, err := findEC2InstancesByID(ctx, client, query)
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		if indexed := e.inventory.findInstances(client.account, query); indexed != nil {
+			results = append(results, *indexed)
+		} else {
+			results = append(results, runEc2Finders(ctx, client, query, ec2InstanceFinders)...)
+		}
+
+		results = append(results, runEc2Finders(ctx, client, query, ec2Finders)...)
+	}
+
+	if isAmiID(query) {
+		if result := e.findImageUsage(ctx, query); result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	// The EC2 API can't do partial or fuzzy searches, but the inventory can
+	if isResourceName(query) {
+		if result := e.inventory.matchInstances(ctx, query); result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func runEc2Finders(ctx context.Context, client ec2Client, query string, finders []func(context.Context, ec2Client, string) (*ResultSet, error)) []ResultSet {
+	results := []ResultSet{}
+
+	for _, find := range finders {
+		result, err := find(ctx, client, query)
 
 		if err != nil {
 			log.Print(err)
@@ -132,29 +127,89 @@ func (e *EC2Resolver) Search(ctx context.Context, query string) []ResultSet {
 		if result != nil {
 			results = append(results, *result)
 		}
+	}
+
+	return results
+}
+
+// findByIP finds the instances, or failing that the network interfaces,
+// that an IP address belongs to. Network interfaces cover resources that
+// aren't instances, e.g. load balancer nodes or RDS instances.
+func (e *EC2Resolver) findByIP(ctx context.Context, ip string) []Result {
+	results := []Result{}
+
+	for _, client := range e.clients {
+		instances, err := findEC2InstancesByIP(ctx, client, ip)
+		if err != nil {
+			log.Print(err)
+		}
+		if instances != nil && len(instances.Results) > 0 {
+			results = append(results, instances.Results...)
+			continue
+		}
 
+		interfaces, err := findNetworkInterfacesByIP(ctx, client, ip)
+		if err != nil {
+			log.Print(err)
+		}
+		results = append(results, interfaces...)
 	}
 
 	return results
 }
 
-func findEC2InstancesByID(ctx context.Context, client ec2SDK, search string) (*ResultSet, error) {
+func findEC2InstancesByID(ctx context.Context, client ec2Client, search string) (*ResultSet, error) {
 	// EC2 instance IDs have a very specific format
 	if !strings.HasPrefix(search, "i-") {
 		return nil, nil
 	}
 
-	// The EC2 API does not allow you to do substring searches
+	// The EC2 API does not allow you to do substring searches, partial IDs
+	// are matched against the inventory instead
 	if len(search) != ExactEc2InstanceIDLength {
 		return nil, nil
 	}
 
+	results, err := describeEC2Instances(ctx, client, &ec2.Filter{
+		Name: aws.String("instance-id"), Values: []*string{aws.String(search)},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultSet{Kind: "ec2.instance", Results: results}, err
+}
+
+func findEC2InstancesByIP(ctx context.Context, client ec2Client, search string) (*ResultSet, error) {
+	if !isIPv4Address(search) {
+		return nil, nil
+	}
+
+	results := []Result{}
+
+	// Filters are ANDed together, so public and private IPs need to be
+	// looked up separately
+	for _, filterName := range []string{"private-ip-address", "ip-address"} {
+		found, err := describeEC2Instances(ctx, client, &ec2.Filter{
+			Name: aws.String(filterName), Values: []*string{aws.String(search)},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, found...)
+	}
+
+	return &ResultSet{Kind: "ec2.instance", Results: results}, nil
+}
+
+func describeEC2Instances(ctx context.Context, client ec2Client, filters ...*ec2.Filter) ([]Result, error) {
 	output, err := client.DescribeInstancesWithContext(
 		ctx,
 		&ec2.DescribeInstancesInput{
-			Filters: []*ec2.Filter{
-				&ec2.Filter{Name: aws.String("instance-id"), Values: []*string{aws.String(search)}},
-			},
+			Filters: filters,
 		},
 	)
 
@@ -167,54 +222,240 @@ func findEC2InstancesByID(ctx context.Context, client ec2SDK, search string) (*R
 
 	for _, reservation := range output.Reservations {
 		for _, instance := range reservation.Instances {
-			publicIpAddresses := []string{}
-			privateIpAddresses := []string{}
-
-			// Stopped instances do not appear to have network interfaces
-			if instance.NetworkInterfaces != nil {
-				for _, networkInterface := range instance.NetworkInterfaces {
-					if networkInterface == nil {
-						continue
-					}
-
-					if networkInterface.Association != nil {
-						publicIpAddresses = append(publicIpAddresses, *networkInterface.Association.PublicIp)
-					}
-
-					if networkInterface.PrivateIpAddresses != nil {
-						for _, privateIp := range networkInterface.PrivateIpAddresses {
-							privateIpAddresses = append(privateIpAddresses, *privateIp.PrivateIpAddress)
-						}
-					}
-				}
+			results = append(results, ec2InstanceToResult(client.account, instance))
+		}
+	}
+
+	return results, nil
+}
+
+func ec2InstanceToResult(account Account, instance *ec2.Instance) Result {
+	publicIpAddresses := []string{}
+	privateIpAddresses := []string{}
+
+	// Stopped instances do not appear to have network interfaces
+	if instance.NetworkInterfaces != nil {
+		for _, networkInterface := range instance.NetworkInterfaces {
+			if networkInterface == nil {
+				continue
 			}
 
-			result := Result{
-				Kind: "ec2.instance",
-				Metadata: map[string][]string{
-					"instance_id":    []string{*instance.InstanceId},
-					"ami_id":         []string{*instance.ImageId},
-					"instance_type":  []string{*instance.InstanceType},
-					"instance_state": []string{*instance.State.Name},
-					"az":             []string{*instance.Placement.AvailabilityZone},
-					"public_ips":     publicIpAddresses,
-					"private_ips":    privateIpAddresses,
-				},
-				Links: map[string]string{
-					"ec2_console":     ec2ConsoleLink("us-east-1", *instance.InstanceId),
-					"config_timeline": ec2ConfigTimelineLink("us-east-1", *instance.InstanceId),
-				},
+			if networkInterface.Association != nil {
+				publicIpAddresses = append(publicIpAddresses, *networkInterface.Association.PublicIp)
 			}
 
-			for _, tag := range instance.Tags {
-				result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+			if networkInterface.PrivateIpAddresses != nil {
+				for _, privateIp := range networkInterface.PrivateIpAddresses {
+					privateIpAddresses = append(privateIpAddresses, *privateIp.PrivateIpAddress)
+				}
 			}
+		}
+	}
 
-			results = append(results, result)
+	result := newResult("ec2.instance", account)
+	result.Metadata["instance_id"] = []string{*instance.InstanceId}
+	result.Metadata["ami_id"] = []string{*instance.ImageId}
+	result.Metadata["instance_type"] = []string{*instance.InstanceType}
+	result.Metadata["instance_state"] = []string{*instance.State.Name}
+	result.Metadata["az"] = []string{*instance.Placement.AvailabilityZone}
+	result.Metadata["public_ips"] = publicIpAddresses
+	result.Metadata["private_ips"] = privateIpAddresses
+
+	if stoppedAt, ok := parseStateTransitionTime(aws.StringValue(instance.StateTransitionReason)); ok && *instance.State.Name == ec2.InstanceStateNameStopped {
+		result.Metadata["stopped_at"] = []string{stoppedAt.Format(time.RFC3339)}
+	}
+
+	// The size/type of volumes isn't included here, search for the volume
+	// ID to find out more about it
+	blockDevices := []string{}
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
 		}
+		blockDevices = append(blockDevices, fmt.Sprintf("%s %s", aws.StringValue(mapping.DeviceName), aws.StringValue(mapping.Ebs.VolumeId)))
 	}
+	result.Metadata["block_devices"] = blockDevices
 
-	return &ResultSet{Kind: "ec2.instance", Results: results}, err
+	securityGroups := []string{}
+	for _, group := range instance.SecurityGroups {
+		securityGroups = append(securityGroups, fmt.Sprintf("%s (%s)", aws.StringValue(group.GroupId), aws.StringValue(group.GroupName)))
+	}
+	sort.Strings(securityGroups)
+	result.Metadata["security_groups"] = securityGroups
+
+	result.Links["ec2_console"] = ec2ConsoleLink(account.Region, *instance.InstanceId)
+	result.Links["config_timeline"] = ec2ConfigTimelineLink(account.Region, *instance.InstanceId)
+
+	for _, tag := range instance.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+	}
+
+	if groupName := result.GetMetadata("tag:" + AutoScalingGroupTag); groupName != "" {
+		result.Links["autoscaling_group"] = autoScalingGroupConsoleLink(account.Region, groupName)
+	}
+
+	return result
+}
+
+// stateTransitionTimePattern finds the time in an instance's state
+// transition reason, e.g. `User initiated (2019-03-01 14:00:00 GMT)`
+var stateTransitionTimePattern = regexp.MustCompile(`\((\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) GMT\)`)
+
+// parseStateTransitionTime finds out when an instance last changed state.
+// The API doesn't say so directly, but it's in the reason for stopped
+// instances.
+func parseStateTransitionTime(reason string) (time.Time, bool) {
+	match := stateTransitionTimePattern.FindStringSubmatch(reason)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse("2006-01-02 15:04:05", match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+func findNetworkInterfacesByIP(ctx context.Context, client ec2Client, ip string) ([]Result, error) {
+	results := []Result{}
+
+	for _, filterName := range []string{"addresses.private-ip-address", "association.public-ip"} {
+		output, err := client.DescribeNetworkInterfacesWithContext(
+			ctx,
+			&ec2.DescribeNetworkInterfacesInput{
+				Filters: []*ec2.Filter{
+					&ec2.Filter{Name: aws.String(filterName), Values: []*string{aws.String(ip)}},
+				},
+			},
+		)
+
+		if err != nil {
+			bugsnag.Notify(err)
+			return nil, err
+		}
+
+		for _, networkInterface := range output.NetworkInterfaces {
+			results = append(results, networkInterfaceToResult(client.account, networkInterface))
+		}
+	}
+
+	return results, nil
+}
+
+func networkInterfaceToResult(account Account, networkInterface *ec2.NetworkInterface) Result {
+	interfaceID := aws.StringValue(networkInterface.NetworkInterfaceId)
+
+	result := newResult("ec2.network_interface", account)
+	result.Metadata["interface_id"] = []string{interfaceID}
+	result.Metadata["interface_type"] = []string{aws.StringValue(networkInterface.InterfaceType)}
+	result.Metadata["description"] = []string{aws.StringValue(networkInterface.Description)}
+	result.Metadata["status"] = []string{aws.StringValue(networkInterface.Status)}
+	result.Metadata["vpc_id"] = []string{aws.StringValue(networkInterface.VpcId)}
+	result.Metadata["az"] = []string{aws.StringValue(networkInterface.AvailabilityZone)}
+
+	if networkInterface.Attachment != nil && networkInterface.Attachment.InstanceId != nil {
+		result.Metadata["instance_id"] = []string{*networkInterface.Attachment.InstanceId}
+	}
+
+	if networkInterface.Association != nil && networkInterface.Association.PublicIp != nil {
+		result.Metadata["public_ips"] = []string{*networkInterface.Association.PublicIp}
+	}
+
+	privateIpAddresses := []string{}
+	for _, privateIp := range networkInterface.PrivateIpAddresses {
+		privateIpAddresses = append(privateIpAddresses, aws.StringValue(privateIp.PrivateIpAddress))
+	}
+	result.Metadata["private_ips"] = privateIpAddresses
+
+	result.Links["ec2_console"] = networkInterfaceConsoleLink(account.Region, interfaceID)
+
+	return result
+}
+
+func isEc2InstanceID(query string) bool {
+	return strings.HasPrefix(query, "i-") && len(query) == ExactEc2InstanceIDLength
+}
+
+// parseEc2PrivateHostname turns EC2 private DNS names into the IP or
+// instance ID they contain, and the region the instance is in, e.g.
+//
+//	ip-10-1-2-3.eu-west-2.compute.internal  -> 10.1.2.3 in eu-west-2
+//	ip-10-1-2-3.ec2.internal                -> 10.1.2.3 in us-east-1
+//	i-0123456789abcdef0.eu-west-2.compute.internal
+//
+// The region is empty for short names like `ip-10-1-2-3`, as the instance
+// could be in any region.
+func parseEc2PrivateHostname(query string) (target, region string, ok bool) {
+	hostname := normaliseHostname(query)
+	labels := strings.SplitN(hostname, ".", 2)
+
+	switch {
+	case len(labels) == 1:
+	case labels[1] == "ec2.internal":
+		region = "us-east-1"
+	case strings.HasSuffix(labels[1], ".compute.internal"):
+		region = strings.TrimSuffix(labels[1], ".compute.internal")
+	default:
+		return "", "", false
+	}
+
+	if isEc2InstanceID(labels[0]) {
+		return labels[0], region, true
+	}
+
+	if !strings.HasPrefix(labels[0], "ip-") {
+		return "", "", false
+	}
+
+	ip := strings.Replace(strings.TrimPrefix(labels[0], "ip-"), "-", ".", -1)
+	if !isIPv4Address(ip) {
+		return "", "", false
+	}
+
+	return ip, region, true
+}
+
+func isIPv4Address(search string) bool {
+	ip := net.ParseIP(search)
+
+	return ip != nil && ip.To4() != nil
+}
+
+// privateIPv4Networks are the ranges that can't be reached from the
+// internet, so can't be elastic IPs
+var privateIPv4Networks = []*net.IPNet{
+	mustParseCIDR("10.0.0.0/8"),
+	mustParseCIDR("172.16.0.0/12"),
+	mustParseCIDR("192.168.0.0/16"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("127.0.0.0/8"),
+	mustParseCIDR("169.254.0.0/16"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+func isPrivateIPv4Address(search string) bool {
+	ip := net.ParseIP(search)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range privateIPv4Networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func ec2ConsoleLink(region, search string) string {
@@ -224,3 +465,7 @@ func ec2ConsoleLink(region, search string) string {
 func ec2ConfigTimelineLink(region, instanceId string) string {
 	return fmt.Sprintf("https://console.aws.amazon.com/config/home?region=%s#/timeline/AWS::EC2::Instance/%s/configuration", region, instanceId)
 }
+
+func networkInterfaceConsoleLink(region, interfaceID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ec2/v2/home?region=%s#NIC:search=%s", region, interfaceID)
+}
@@ -0,0 +1,33 @@
+package search
+
+import "testing"
+
+func TestParseEc2PrivateHostname(t *testing.T) {
+	cases := []struct {
+		query  string
+		target string
+		region string
+		ok     bool
+	}{
+		{"ip-10-1-2-3.eu-west-2.compute.internal", "10.1.2.3", "eu-west-2", true},
+		{"ip-10-1-2-3.ec2.internal", "10.1.2.3", "us-east-1", true},
+		{"i-0123456789abcdef0.eu-west-2.compute.internal", "i-0123456789abcdef0", "eu-west-2", true},
+		{"ip-10-1-2-3", "10.1.2.3", "", true},
+		{"IP-10-1-2-3.EU-WEST-2.COMPUTE.INTERNAL.", "10.1.2.3", "eu-west-2", true},
+		{"ip-10-1-2.eu-west-2.compute.internal", "", "", false},
+		{"ip-300-1-2-3.eu-west-2.compute.internal", "", "", false},
+		{"ip-10-1-2-3.example.com", "", "", false},
+		{"web-1.eu-west-2.compute.internal", "", "", false},
+		{"payments", "", "", false},
+	}
+
+	for _, c := range cases {
+		target, region, ok := parseEc2PrivateHostname(c.query)
+		if ok != c.ok || target != c.target || region != c.region {
+			t.Errorf(
+				"expected %s to parse as (%q, %q, %v), got (%q, %q, %v)",
+				c.query, c.target, c.region, c.ok, target, region, ok,
+			)
+		}
+	}
+}
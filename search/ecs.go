@@ -0,0 +1,556 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// ECSServiceEventsToShow is how many of a service's most recent events
+// are included in its result
+const ECSServiceEventsToShow = 5
+
+// DescribeTasks accepts at most 100 tasks per call
+const ecsDescribeTasksBatchSize = 100
+
+// ecsAttachmentPattern matches the description ECS gives the network
+// interfaces it creates for awsvpc tasks, and captures the attachment ID
+var ecsAttachmentPattern = regexp.MustCompile(`^arn:aws:ecs:[^:]+:\d+:attachment/([0-9a-f-]+)$`)
+
+type ecsSDK interface {
+	ListClustersPagesWithContext(ctx aws.Context, input *ecs.ListClustersInput, fn func(*ecs.ListClustersOutput, bool) bool, opts ...request.Option) error
+	ListTasksPagesWithContext(ctx aws.Context, input *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool, opts ...request.Option) error
+	ListContainerInstancesWithContext(ctx aws.Context, input *ecs.ListContainerInstancesInput, opts ...request.Option) (*ecs.ListContainerInstancesOutput, error)
+	DescribeTasksWithContext(ctx aws.Context, input *ecs.DescribeTasksInput, opts ...request.Option) (*ecs.DescribeTasksOutput, error)
+	DescribeServicesWithContext(ctx aws.Context, input *ecs.DescribeServicesInput, opts ...request.Option) (*ecs.DescribeServicesOutput, error)
+	DescribeTaskDefinitionWithContext(ctx aws.Context, input *ecs.DescribeTaskDefinitionInput, opts ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error)
+}
+
+type ecsClient struct {
+	ecsSDK
+	// ec2 finds the network interfaces of awsvpc tasks
+	ec2     ec2SDK
+	account Account
+}
+
+func buildEcsClients(accounts []Account) []ecsClient {
+	clients := []ecsClient{}
+
+	for _, account := range accounts {
+		clients = append(clients, ecsClient{
+			ecsSDK:  ecs.New(account.session, account.config),
+			ec2:     ec2.New(account.session, account.config),
+			account: account,
+		})
+	}
+
+	return clients
+}
+
+func NewEcs(accounts []Account) *ECSResolver {
+	return &ECSResolver{clients: buildEcsClients(accounts)}
+}
+
+// ECSResolver finds ECS tasks and services in every cluster of every
+// account. Tasks can be found by:
+//
+// - the private IP of an awsvpc task
+// - task ARN or ID
+// - container instance ARN or ID, or the EC2 instance ID of a container
+// instance
+//
+// Services are found by name prefixed with `svc:`, as otherwise every
+// word would be looked for in every cluster
+type ECSResolver struct {
+	clients []ecsClient
+}
+
+func (e *ECSResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	serviceName, isServiceQuery := trimQueryPrefix(query, "svc:")
+	isTaskQuery := isIPv4Address(query) || isEc2InstanceID(query) || isEcsTaskOrContainerInstance(query)
+
+	if !isTaskQuery && !isServiceQuery {
+		return results
+	}
+
+	for _, client := range e.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		var result *ResultSet
+		var err error
+		if isTaskQuery {
+			result, err = findEcsTasks(ctx, client, query)
+		} else {
+			result, err = findEcsServicesByName(ctx, client, serviceName)
+		}
+
+		if err != nil {
+			log.Print(err)
+		}
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func listEcsClusters(ctx context.Context, client ecsClient) ([]string, error) {
+	clusters := []string{}
+
+	err := client.ListClustersPagesWithContext(
+		ctx,
+		&ecs.ListClustersInput{},
+		func(page *ecs.ListClustersOutput, lastPage bool) bool {
+			clusters = append(clusters, aws.StringValueSlice(page.ClusterArns)...)
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+func findEcsTasks(ctx context.Context, client ecsClient, query string) (*ResultSet, error) {
+	var tasks []*ecs.Task
+	var err error
+
+	if isIPv4Address(query) {
+		tasks, err = findEcsTasksByIP(ctx, client, query)
+	} else {
+		tasks, err = findEcsTasksInClusters(ctx, client, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	results := []Result{}
+	// Tasks only list their containers' images in their task definition,
+	// which is usually shared by many of them
+	images := map[string][]string{}
+
+	for _, task := range tasks {
+		result := ecsTaskToResult(client.account, task)
+
+		definition := aws.StringValue(task.TaskDefinitionArn)
+		if _, ok := images[definition]; !ok {
+			if images[definition], err = ecsTaskDefinitionImages(ctx, client, definition); err != nil {
+				return nil, err
+			}
+		}
+		result.Metadata["container_images"] = images[definition]
+
+		results = append(results, result)
+	}
+
+	return &ResultSet{Kind: "ecs.task", Results: results}, nil
+}
+
+func findEcsTasksInClusters(ctx context.Context, client ecsClient, query string) ([]*ecs.Task, error) {
+	clusters, err := listEcsClusters(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []*ecs.Task{}
+	for _, cluster := range clusters {
+		var found []*ecs.Task
+
+		switch {
+		case isEc2InstanceID(query):
+			found, err = findEcsTasksByEc2Instance(ctx, client, cluster, query)
+		case strings.Contains(query, ":container-instance/"):
+			found, err = findEcsTasksByContainerInstance(ctx, client, cluster, query)
+		default:
+			// Task IDs and container instance IDs look the same, so we
+			// have to try both
+			found, err = describeEcsTasks(ctx, client, cluster, []string{query})
+			if err == nil && len(found) == 0 && !strings.HasPrefix(query, "arn:") {
+				found, err = findEcsTasksByContainerInstance(ctx, client, cluster, query)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, found...)
+	}
+
+	return tasks, nil
+}
+
+// findEcsTasksByIP looks for the network interface ECS created for an
+// awsvpc task with the IP, so that IPs that don't belong to a task don't
+// search any clusters. The ECS API can't find tasks by their attachment,
+// so the tasks that could own it are described: those on the instance the
+// interface is attached to, or the cluster's Fargate tasks.
+func findEcsTasksByIP(ctx context.Context, client ecsClient, ip string) ([]*ecs.Task, error) {
+	output, err := client.ec2.DescribeNetworkInterfacesWithContext(
+		ctx,
+		&ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{Name: aws.String("addresses.private-ip-address"), Values: []*string{aws.String(ip)}},
+			},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	tasks := []*ecs.Task{}
+	for _, networkInterface := range output.NetworkInterfaces {
+		match := ecsAttachmentPattern.FindStringSubmatch(aws.StringValue(networkInterface.Description))
+		if match == nil {
+			continue
+		}
+
+		clusters, err := listEcsClusters(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cluster := range clusters {
+			var candidates []*ecs.Task
+			if attachment := networkInterface.Attachment; attachment != nil && attachment.InstanceId != nil {
+				candidates, err = findEcsTasksByEc2Instance(ctx, client, cluster, *attachment.InstanceId)
+			} else {
+				candidates, err = findEcsFargateTasks(ctx, client, cluster)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			for _, task := range candidates {
+				if ecsTaskHasAttachment(task, match[1]) {
+					tasks = append(tasks, task)
+				}
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+func findEcsFargateTasks(ctx context.Context, client ecsClient, cluster string) ([]*ecs.Task, error) {
+	taskArns, err := listEcsTasks(ctx, client, &ecs.ListTasksInput{
+		Cluster:    aws.String(cluster),
+		LaunchType: aws.String(ecs.LaunchTypeFargate),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return describeEcsTasks(ctx, client, cluster, taskArns)
+}
+
+func ecsTaskHasAttachment(task *ecs.Task, attachmentID string) bool {
+	for _, attachment := range task.Attachments {
+		if aws.StringValue(attachment.Id) == attachmentID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findEcsTasksByEc2Instance(ctx context.Context, client ecsClient, cluster, instanceID string) ([]*ecs.Task, error) {
+	output, err := client.ListContainerInstancesWithContext(
+		ctx,
+		&ecs.ListContainerInstancesInput{
+			Cluster: aws.String(cluster),
+			Filter:  aws.String(fmt.Sprintf("ec2InstanceId == %s", instanceID)),
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	tasks := []*ecs.Task{}
+	for _, containerInstance := range output.ContainerInstanceArns {
+		found, err := findEcsTasksByContainerInstance(ctx, client, cluster, aws.StringValue(containerInstance))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, found...)
+	}
+
+	return tasks, nil
+}
+
+func findEcsTasksByContainerInstance(ctx context.Context, client ecsClient, cluster, containerInstance string) ([]*ecs.Task, error) {
+	taskArns, err := listEcsTasks(ctx, client, &ecs.ListTasksInput{
+		Cluster:           aws.String(cluster),
+		ContainerInstance: aws.String(containerInstance),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return describeEcsTasks(ctx, client, cluster, taskArns)
+}
+
+func listEcsTasks(ctx context.Context, client ecsClient, input *ecs.ListTasksInput) ([]string, error) {
+	taskArns := []string{}
+
+	err := client.ListTasksPagesWithContext(
+		ctx,
+		input,
+		func(page *ecs.ListTasksOutput, lastPage bool) bool {
+			taskArns = append(taskArns, aws.StringValueSlice(page.TaskArns)...)
+			return true
+		},
+	)
+	if err != nil {
+		// ECS returns an error rather than an empty list when asked for
+		// the tasks of a container instance that isn't in the cluster
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecs.ErrCodeInvalidParameterException {
+			return taskArns, nil
+		}
+
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	return taskArns, nil
+}
+
+func describeEcsTasks(ctx context.Context, client ecsClient, cluster string, tasks []string) ([]*ecs.Task, error) {
+	described := []*ecs.Task{}
+
+	for start := 0; start < len(tasks); start += ecsDescribeTasksBatchSize {
+		end := start + ecsDescribeTasksBatchSize
+		if end > len(tasks) {
+			end = len(tasks)
+		}
+
+		output, err := client.DescribeTasksWithContext(
+			ctx,
+			&ecs.DescribeTasksInput{
+				Cluster: aws.String(cluster),
+				Tasks:   aws.StringSlice(tasks[start:end]),
+			},
+		)
+		if err != nil {
+			bugsnag.Notify(err)
+			return nil, err
+		}
+
+		// Tasks that aren't in this cluster are reported in output.Failures
+		described = append(described, output.Tasks...)
+	}
+
+	return described, nil
+}
+
+func ecsTaskPrivateIPs(task *ecs.Task) []string {
+	ips := []string{}
+
+	for _, attachment := range task.Attachments {
+		for _, detail := range attachment.Details {
+			if aws.StringValue(detail.Name) == "privateIPv4Address" {
+				ips = append(ips, aws.StringValue(detail.Value))
+			}
+		}
+	}
+
+	return ips
+}
+
+func ecsTaskToResult(account Account, task *ecs.Task) Result {
+	taskArn := aws.StringValue(task.TaskArn)
+	taskID := taskArn[strings.LastIndex(taskArn, "/")+1:]
+	cluster := ecsResourceName(aws.StringValue(task.ClusterArn))
+
+	containers := []string{}
+	for _, container := range task.Containers {
+		containers = append(containers, fmt.Sprintf(
+			"%s (%s)",
+			aws.StringValue(container.Name),
+			aws.StringValue(container.LastStatus),
+		))
+	}
+
+	result := newResult("ecs.task", account)
+	result.Metadata["task_id"] = []string{taskID}
+	result.Metadata["task_arn"] = []string{taskArn}
+	result.Metadata["cluster"] = []string{cluster}
+	result.Metadata["task_definition"] = []string{ecsResourceName(aws.StringValue(task.TaskDefinitionArn))}
+	result.Metadata["last_status"] = []string{aws.StringValue(task.LastStatus)}
+	result.Metadata["desired_status"] = []string{aws.StringValue(task.DesiredStatus)}
+	result.Metadata["launch_type"] = []string{aws.StringValue(task.LaunchType)}
+	result.Metadata["containers"] = containers
+	result.Metadata["private_ips"] = ecsTaskPrivateIPs(task)
+
+	// Tasks started by a service are in the group "service:{name}"
+	if service, ok := trimQueryPrefix(aws.StringValue(task.Group), "service:"); ok {
+		result.Metadata["service"] = []string{service}
+	}
+	if task.ContainerInstanceArn != nil {
+		result.Metadata["container_instance"] = []string{ecsResourceName(*task.ContainerInstanceArn)}
+	}
+	if task.StartedAt != nil {
+		result.Metadata["started_at"] = []string{task.StartedAt.UTC().Format("2006-01-02 15:04:05 MST")}
+	}
+
+	result.Links["ecs_console"] = ecsTaskConsoleLink(account.Region, cluster, taskID)
+
+	return result
+}
+
+func findEcsServicesByName(ctx context.Context, client ecsClient, name string) (*ResultSet, error) {
+	clusters, err := listEcsClusters(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+
+	for _, cluster := range clusters {
+		output, err := client.DescribeServicesWithContext(
+			ctx,
+			&ecs.DescribeServicesInput{
+				Cluster:  aws.String(cluster),
+				Services: []*string{aws.String(name)},
+			},
+		)
+		if err != nil {
+			bugsnag.Notify(err)
+			return nil, err
+		}
+
+		for _, service := range output.Services {
+			// Deleted services hang around for a while as INACTIVE
+			if aws.StringValue(service.Status) == "INACTIVE" {
+				continue
+			}
+
+			result := ecsServiceToResult(client.account, service)
+
+			images, err := ecsTaskDefinitionImages(ctx, client, aws.StringValue(service.TaskDefinition))
+			if err != nil {
+				return nil, err
+			}
+			result.Metadata["container_images"] = images
+
+			results = append(results, result)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	return &ResultSet{Kind: "ecs.service", Results: results}, nil
+}
+
+func ecsTaskDefinitionImages(ctx context.Context, client ecsClient, taskDefinition string) ([]string, error) {
+	output, err := client.DescribeTaskDefinitionWithContext(
+		ctx,
+		&ecs.DescribeTaskDefinitionInput{TaskDefinition: aws.String(taskDefinition)},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	images := []string{}
+	for _, container := range output.TaskDefinition.ContainerDefinitions {
+		images = append(images, aws.StringValue(container.Image))
+	}
+
+	return images, nil
+}
+
+func ecsServiceToResult(account Account, service *ecs.Service) Result {
+	name := aws.StringValue(service.ServiceName)
+	cluster := ecsResourceName(aws.StringValue(service.ClusterArn))
+
+	// Events are returned newest first
+	events := []string{}
+	for i, event := range service.Events {
+		if i >= ECSServiceEventsToShow {
+			break
+		}
+		events = append(events, fmt.Sprintf(
+			"%s %s",
+			aws.TimeValue(event.CreatedAt).UTC().Format("2006-01-02 15:04"),
+			aws.StringValue(event.Message),
+		))
+	}
+
+	result := newResult("ecs.service", account)
+	result.Metadata["name"] = []string{name}
+	result.Metadata["cluster"] = []string{cluster}
+	result.Metadata["status"] = []string{aws.StringValue(service.Status)}
+	result.Metadata["task_definition"] = []string{ecsResourceName(aws.StringValue(service.TaskDefinition))}
+	result.Metadata["launch_type"] = []string{aws.StringValue(service.LaunchType)}
+	result.Metadata["desired_count"] = []string{strconv.FormatInt(aws.Int64Value(service.DesiredCount), 10)}
+	result.Metadata["running_count"] = []string{strconv.FormatInt(aws.Int64Value(service.RunningCount), 10)}
+	result.Metadata["pending_count"] = []string{strconv.FormatInt(aws.Int64Value(service.PendingCount), 10)}
+	result.Metadata["recent_events"] = events
+
+	result.Links["ecs_console"] = ecsServiceConsoleLink(account.Region, cluster, name)
+
+	return result
+}
+
+// isEcsTaskOrContainerInstance matches the ARN or ID of a task or container
+// instance. IDs are either UUIDs, or 32 character hex strings.
+func isEcsTaskOrContainerInstance(query string) bool {
+	if strings.HasPrefix(query, "arn:") {
+		return strings.Contains(query, ":task/") || strings.Contains(query, ":container-instance/")
+	}
+
+	id := strings.Replace(query, "-", "", -1)
+	if len(id) != 32 {
+		return false
+	}
+	if id != query && len(query) != 36 {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ecsResourceName turns an ARN like
+// arn:aws:ecs:us-east-1:123456789012:task-definition/web:42 into web:42
+func ecsResourceName(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+func ecsTaskConsoleLink(region, cluster, taskID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ecs/home?region=%s#/clusters/%s/tasks/%s/details", region, cluster, taskID)
+}
+
+func ecsServiceConsoleLink(region, cluster, service string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ecs/home?region=%s#/clusters/%s/services/%s/details", region, cluster, service)
+}
@@ -0,0 +1,154 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// findElasticIPsByIP maps a public IP to the elastic IP allocation it
+// belongs to, and whatever the allocation is associated with. Public IPs
+// in firewall allowlists often belong to NAT gateways rather than
+// instances. Private IPs can't be elastic IPs, so aren't looked up.
+func findElasticIPsByIP(ctx context.Context, client ec2Client, search string) (*ResultSet, error) {
+	if !isIPv4Address(search) || isPrivateIPv4Address(search) {
+		return nil, nil
+	}
+
+	output, err := client.DescribeAddressesWithContext(
+		ctx,
+		&ec2.DescribeAddressesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{Name: aws.String("public-ip"), Values: []*string{aws.String(search)}},
+			},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(output.Addresses) == 0 {
+		return nil, nil
+	}
+
+	results := []Result{}
+	// NAT gateways are only listed once, however many addresses need them
+	var gateways []*ec2.NatGateway
+
+	for _, address := range output.Addresses {
+		result := elasticIPToResult(client.account, address)
+
+		// EIPs used by NAT gateways are associated with the gateway's
+		// network interface, rather than the gateway itself
+		if address.NetworkInterfaceId != nil && address.InstanceId == nil {
+			if gateways == nil {
+				if gateways, err = findNatGatewaysByPublicIP(ctx, client, search); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, gateway := range gateways {
+				addNatGateway(client.account, result, gateway)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return &ResultSet{Kind: "ec2.elastic_ip", Results: results}, nil
+}
+
+func elasticIPToResult(account Account, address *ec2.Address) Result {
+	associationType := "none"
+	switch {
+	case address.InstanceId != nil:
+		associationType = "instance"
+	case address.NetworkInterfaceId != nil:
+		associationType = "network_interface"
+	}
+
+	result := newResult("ec2.elastic_ip", account)
+	result.Metadata["public_ip"] = []string{aws.StringValue(address.PublicIp)}
+	result.Metadata["allocation_id"] = []string{aws.StringValue(address.AllocationId)}
+	result.Metadata["domain"] = []string{aws.StringValue(address.Domain)}
+	result.Metadata["association_type"] = []string{associationType}
+	// Unassociated EIPs are charged for, so make them easy to spot
+	result.Metadata["associated"] = []string{strconv.FormatBool(associationType != "none")}
+
+	if address.AssociationId != nil {
+		result.Metadata["association_id"] = []string{*address.AssociationId}
+	}
+	if address.InstanceId != nil {
+		result.Metadata["instance_id"] = []string{*address.InstanceId}
+		result.Links["instance"] = ec2ConsoleLink(account.Region, *address.InstanceId)
+	}
+	if address.NetworkInterfaceId != nil {
+		result.Metadata["interface_id"] = []string{*address.NetworkInterfaceId}
+		result.Links["network_interface"] = networkInterfaceConsoleLink(account.Region, *address.NetworkInterfaceId)
+	}
+	if address.PrivateIpAddress != nil {
+		result.Metadata["private_ips"] = []string{*address.PrivateIpAddress}
+	}
+
+	for _, tag := range address.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+	}
+
+	result.Links["ec2_console"] = elasticIPConsoleLink(account.Region, aws.StringValue(address.PublicIp))
+
+	return result
+}
+
+// findNatGatewaysByPublicIP lists every NAT gateway, as they can't be
+// filtered by IP
+func findNatGatewaysByPublicIP(ctx context.Context, client ec2Client, ip string) ([]*ec2.NatGateway, error) {
+	gateways := []*ec2.NatGateway{}
+
+	err := client.DescribeNatGatewaysPagesWithContext(
+		ctx,
+		&ec2.DescribeNatGatewaysInput{},
+		func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+			for _, gateway := range page.NatGateways {
+				for _, address := range gateway.NatGatewayAddresses {
+					if aws.StringValue(address.PublicIp) == ip {
+						gateways = append(gateways, gateway)
+						break
+					}
+				}
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	return gateways, nil
+}
+
+// addNatGateway records that an elastic IP is used by a NAT gateway
+func addNatGateway(account Account, address Result, gateway *ec2.NatGateway) {
+	gatewayID := aws.StringValue(gateway.NatGatewayId)
+
+	address.Metadata["association_type"] = []string{"nat_gateway"}
+	address.Metadata["nat_gateway_id"] = []string{gatewayID}
+	address.Metadata["nat_gateway_state"] = []string{aws.StringValue(gateway.State)}
+	address.Metadata["vpc_id"] = []string{aws.StringValue(gateway.VpcId)}
+	address.Metadata["subnet_id"] = []string{aws.StringValue(gateway.SubnetId)}
+
+	address.Links["nat_gateway"] = natGatewayConsoleLink(account.Region, gatewayID)
+}
+
+func elasticIPConsoleLink(region, publicIP string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ec2/v2/home?region=%s#Addresses:search=%s", region, publicIP)
+}
+
+func natGatewayConsoleLink(region, gatewayID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/vpc/home?region=%s#NatGateways:search=%s", region, gatewayID)
+}
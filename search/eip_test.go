@@ -0,0 +1,130 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// fakeAddressesSDK answers DescribeAddresses and DescribeNatGateways, and
+// counts how often each is called
+type fakeAddressesSDK struct {
+	ec2SDK
+	addresses    []*ec2.Address
+	gateways     []*ec2.NatGateway
+	addressCalls int
+	gatewayCalls int
+}
+
+func (f *fakeAddressesSDK) DescribeAddressesWithContext(ctx aws.Context, input *ec2.DescribeAddressesInput, opts ...request.Option) (*ec2.DescribeAddressesOutput, error) {
+	f.addressCalls++
+	return &ec2.DescribeAddressesOutput{Addresses: f.addresses}, nil
+}
+
+func (f *fakeAddressesSDK) DescribeNatGatewaysPagesWithContext(ctx aws.Context, input *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool, opts ...request.Option) error {
+	f.gatewayCalls++
+	fn(&ec2.DescribeNatGatewaysOutput{NatGateways: f.gateways}, true)
+	return nil
+}
+
+func TestFindElasticIPsByIP(t *testing.T) {
+	account := Account{Alias: "production", ID: "123456789012", Region: "eu-west-2"}
+
+	natAddress := func(allocationID string) *ec2.Address {
+		return &ec2.Address{
+			PublicIp:           aws.String("203.0.113.10"),
+			AllocationId:       aws.String(allocationID),
+			NetworkInterfaceId: aws.String("eni-0123456789abcdef0"),
+		}
+	}
+	gateway := &ec2.NatGateway{
+		NatGatewayId:        aws.String("nat-0123456789abcdef0"),
+		State:               aws.String("available"),
+		VpcId:               aws.String("vpc-0123456789abcdef0"),
+		NatGatewayAddresses: []*ec2.NatGatewayAddress{{PublicIp: aws.String("203.0.113.10")}},
+	}
+
+	cases := []struct {
+		name         string
+		query        string
+		addresses    []*ec2.Address
+		results      int
+		addressCalls int
+		gatewayCalls int
+		association  string
+	}{
+		{
+			name:         "Private IPs can't be elastic IPs",
+			query:        "10.1.2.3",
+			addresses:    []*ec2.Address{natAddress("eipalloc-1")},
+			addressCalls: 0,
+			gatewayCalls: 0,
+		},
+		{
+			name:         "Addresses used by NAT gateways",
+			query:        "203.0.113.10",
+			addresses:    []*ec2.Address{natAddress("eipalloc-1"), natAddress("eipalloc-2")},
+			results:      2,
+			addressCalls: 1,
+			gatewayCalls: 1,
+			association:  "nat_gateway",
+		},
+		{
+			name:  "Addresses used by instances",
+			query: "203.0.113.10",
+			addresses: []*ec2.Address{{
+				PublicIp:           aws.String("203.0.113.10"),
+				AllocationId:       aws.String("eipalloc-1"),
+				InstanceId:         aws.String("i-0123456789abcdef0"),
+				NetworkInterfaceId: aws.String("eni-0123456789abcdef0"),
+			}},
+			results:      1,
+			addressCalls: 1,
+			gatewayCalls: 0,
+			association:  "instance",
+		},
+		{
+			name:         "Not an elastic IP",
+			query:        "203.0.113.20",
+			addressCalls: 1,
+			gatewayCalls: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fake := &fakeAddressesSDK{addresses: c.addresses, gateways: []*ec2.NatGateway{gateway}}
+
+			set, err := findElasticIPsByIP(context.Background(), ec2Client{ec2SDK: fake, account: account}, c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			found := []Result{}
+			if set != nil {
+				found = set.Results
+			}
+			if len(found) != c.results {
+				t.Fatalf("expected %d results, got %d", c.results, len(found))
+			}
+			if fake.addressCalls != c.addressCalls || fake.gatewayCalls != c.gatewayCalls {
+				t.Errorf(
+					"expected %d DescribeAddresses and %d DescribeNatGateways calls, got %d and %d",
+					c.addressCalls, c.gatewayCalls, fake.addressCalls, fake.gatewayCalls,
+				)
+			}
+
+			for _, result := range found {
+				if got := result.GetMetadata("association_type"); got != c.association {
+					t.Errorf("expected the association to be %q, got %q", c.association, got)
+				}
+				if c.association == "nat_gateway" && result.GetMetadata("nat_gateway_id") != "nat-0123456789abcdef0" {
+					t.Errorf("expected the NAT gateway to be recorded, got %q", result.GetMetadata("nat_gateway_id"))
+				}
+			}
+		})
+	}
+}
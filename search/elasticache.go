@@ -0,0 +1,320 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/elasticache"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+type elastiCacheSDK interface {
+	DescribeCacheClustersWithContext(ctx aws.Context, input *elasticache.DescribeCacheClustersInput, opts ...request.Option) (*elasticache.DescribeCacheClustersOutput, error)
+	DescribeCacheClustersPagesWithContext(ctx aws.Context, input *elasticache.DescribeCacheClustersInput, fn func(*elasticache.DescribeCacheClustersOutput, bool) bool, opts ...request.Option) error
+	DescribeReplicationGroupsPagesWithContext(ctx aws.Context, input *elasticache.DescribeReplicationGroupsInput, fn func(*elasticache.DescribeReplicationGroupsOutput, bool) bool, opts ...request.Option) error
+}
+
+type elastiCacheClient struct {
+	elastiCacheSDK
+	account Account
+}
+
+func buildElastiCacheClients(accounts []Account) []elastiCacheClient {
+	clients := []elastiCacheClient{}
+
+	for _, account := range accounts {
+		svc := elasticache.New(account.session, account.config)
+
+		clients = append(clients, elastiCacheClient{elastiCacheSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+func NewElastiCache(accounts []Account) *ElastiCacheResolver {
+	return &ElastiCacheResolver{clients: buildElastiCacheClients(accounts)}
+}
+
+// ElastiCacheResolver finds the cache cluster or replication group behind
+// an endpoint, e.g. `payments.abc123.ng.0001.euw2.cache.amazonaws.com`, or
+// by its ID, e.g. `cache:payments`
+type ElastiCacheResolver struct {
+	clients []elastiCacheClient
+}
+
+func (e *ElastiCacheResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	id, isIDQuery := trimQueryPrefix(query, "cache:")
+	if (isIDQuery && id == "") || (!isIDQuery && !isElastiCacheHostname(query)) {
+		return results
+	}
+
+	for _, client := range e.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		var result *ResultSet
+		var err error
+		if isIDQuery {
+			result, err = findCacheByID(ctx, client, id)
+		} else {
+			result, err = findCacheByEndpoint(ctx, client, normaliseHostname(query))
+		}
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+// findCacheByEndpoint matches the hostname against the endpoints of every
+// replication group and cache cluster. Endpoints include an abbreviated
+// region and a random ID, so we can't work out the cluster from the
+// hostname alone.
+func findCacheByEndpoint(ctx context.Context, client elastiCacheClient, hostname string) (*ResultSet, error) {
+	results := []Result{}
+
+	var groups []*elasticache.ReplicationGroup
+	err := client.DescribeReplicationGroupsPagesWithContext(
+		ctx,
+		&elasticache.DescribeReplicationGroupsInput{},
+		func(page *elasticache.DescribeReplicationGroupsOutput, lastPage bool) bool {
+			for _, group := range page.ReplicationGroups {
+				if replicationGroupEndpointRole(group, hostname) != "" {
+					groups = append(groups, group)
+				}
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	matchedGroups := map[string]bool{}
+	for _, group := range groups {
+		matchedGroups[aws.StringValue(group.ReplicationGroupId)] = true
+
+		// Engine details are only available on the group's member clusters
+		var member *elasticache.CacheCluster
+		if len(group.MemberClusters) > 0 {
+			member, err = describeCacheCluster(ctx, client, *group.MemberClusters[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		results = append(results, replicationGroupToResult(client.account, group, member, hostname))
+	}
+
+	err = client.DescribeCacheClustersPagesWithContext(
+		ctx,
+		&elasticache.DescribeCacheClustersInput{ShowCacheNodeInfo: aws.Bool(true)},
+		func(page *elasticache.DescribeCacheClustersOutput, lastPage bool) bool {
+			for _, cluster := range page.CacheClusters {
+				// Nodes in a replication group we've already found would
+				// be duplicates
+				if matchedGroups[aws.StringValue(cluster.ReplicationGroupId)] {
+					continue
+				}
+
+				if cacheClusterHasEndpoint(cluster, hostname) {
+					results = append(results, cacheClusterToResult(client.account, cluster))
+				}
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	return &ResultSet{Kind: "elasticache.cluster", Results: results}, nil
+}
+
+// findCacheByID looks for a replication group with the ID, and then for a
+// cache cluster
+func findCacheByID(ctx context.Context, client elastiCacheClient, id string) (*ResultSet, error) {
+	var group *elasticache.ReplicationGroup
+	err := client.DescribeReplicationGroupsPagesWithContext(
+		ctx,
+		&elasticache.DescribeReplicationGroupsInput{ReplicationGroupId: aws.String(id)},
+		func(page *elasticache.DescribeReplicationGroupsOutput, lastPage bool) bool {
+			if len(page.ReplicationGroups) > 0 {
+				group = page.ReplicationGroups[0]
+			}
+			return false
+		},
+	)
+	if err != nil && awsErrorCode(err) != elasticache.ErrCodeReplicationGroupNotFoundFault {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if group != nil {
+		var member *elasticache.CacheCluster
+		if len(group.MemberClusters) > 0 {
+			member, err = describeCacheCluster(ctx, client, *group.MemberClusters[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return &ResultSet{
+			Kind:    "elasticache.cluster",
+			Results: []Result{replicationGroupToResult(client.account, group, member, "")},
+		}, nil
+	}
+
+	cluster, err := describeCacheCluster(ctx, client, id)
+	if err != nil || cluster == nil {
+		return nil, err
+	}
+
+	return &ResultSet{
+		Kind:    "elasticache.cluster",
+		Results: []Result{cacheClusterToResult(client.account, cluster)},
+	}, nil
+}
+
+func describeCacheCluster(ctx context.Context, client elastiCacheClient, clusterID string) (*elasticache.CacheCluster, error) {
+	output, err := client.DescribeCacheClustersWithContext(
+		ctx,
+		&elasticache.DescribeCacheClustersInput{CacheClusterId: aws.String(clusterID)},
+	)
+	if err != nil {
+		if awsErrorCode(err) == elasticache.ErrCodeCacheClusterNotFoundFault {
+			return nil, nil
+		}
+
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(output.CacheClusters) == 0 {
+		return nil, nil
+	}
+
+	return output.CacheClusters[0], nil
+}
+
+// replicationGroupEndpointRole returns which of the group's endpoints
+// hostname is, or an empty string if it isn't one of them
+func replicationGroupEndpointRole(group *elasticache.ReplicationGroup, hostname string) string {
+	if endpointMatches(group.ConfigurationEndpoint, hostname) {
+		return "configuration"
+	}
+
+	for _, nodeGroup := range group.NodeGroups {
+		if endpointMatches(nodeGroup.PrimaryEndpoint, hostname) {
+			return "primary"
+		}
+
+		for _, member := range nodeGroup.NodeGroupMembers {
+			if endpointMatches(member.ReadEndpoint, hostname) {
+				return fmt.Sprintf("node %s (%s)", aws.StringValue(member.CacheClusterId), aws.StringValue(member.CurrentRole))
+			}
+		}
+	}
+
+	return ""
+}
+
+func cacheClusterHasEndpoint(cluster *elasticache.CacheCluster, hostname string) bool {
+	if endpointMatches(cluster.ConfigurationEndpoint, hostname) {
+		return true
+	}
+
+	for _, node := range cluster.CacheNodes {
+		if endpointMatches(node.Endpoint, hostname) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func endpointMatches(endpoint *elasticache.Endpoint, hostname string) bool {
+	return endpoint != nil && normaliseHostname(aws.StringValue(endpoint.Address)) == hostname
+}
+
+func replicationGroupToResult(account Account, group *elasticache.ReplicationGroup, member *elasticache.CacheCluster, hostname string) Result {
+	groupID := aws.StringValue(group.ReplicationGroupId)
+
+	result := newResult("elasticache.cluster", account)
+	result.Metadata["cluster_id"] = []string{groupID}
+	result.Metadata["replication_group_id"] = []string{groupID}
+	result.Metadata["description"] = []string{aws.StringValue(group.Description)}
+	result.Metadata["status"] = []string{aws.StringValue(group.Status)}
+	result.Metadata["endpoint"] = []string{replicationGroupEndpointRole(group, hostname)}
+	result.Metadata["member_clusters"] = aws.StringValueSlice(group.MemberClusters)
+	result.Metadata["num_nodes"] = []string{strconv.Itoa(len(group.MemberClusters))}
+	result.Metadata["automatic_failover"] = []string{aws.StringValue(group.AutomaticFailover)}
+	result.Metadata["cluster_mode"] = []string{strconv.FormatBool(aws.BoolValue(group.ClusterEnabled))}
+
+	if member != nil {
+		result.Metadata["engine"] = []string{aws.StringValue(member.Engine)}
+		result.Metadata["engine_version"] = []string{aws.StringValue(member.EngineVersion)}
+		result.Metadata["node_type"] = []string{aws.StringValue(member.CacheNodeType)}
+	}
+
+	result.Links["elasticache_console"] = elastiCacheConsoleLink(account.Region, "redis", groupID)
+
+	return result
+}
+
+func cacheClusterToResult(account Account, cluster *elasticache.CacheCluster) Result {
+	clusterID := aws.StringValue(cluster.CacheClusterId)
+	engine := aws.StringValue(cluster.Engine)
+
+	result := newResult("elasticache.cluster", account)
+	result.Metadata["cluster_id"] = []string{clusterID}
+	result.Metadata["status"] = []string{aws.StringValue(cluster.CacheClusterStatus)}
+	result.Metadata["engine"] = []string{engine}
+	result.Metadata["engine_version"] = []string{aws.StringValue(cluster.EngineVersion)}
+	result.Metadata["node_type"] = []string{aws.StringValue(cluster.CacheNodeType)}
+	result.Metadata["num_nodes"] = []string{strconv.FormatInt(aws.Int64Value(cluster.NumCacheNodes), 10)}
+	result.Metadata["az"] = []string{aws.StringValue(cluster.PreferredAvailabilityZone)}
+
+	if cluster.ReplicationGroupId != nil {
+		result.Metadata["replication_group_id"] = []string{*cluster.ReplicationGroupId}
+	}
+
+	for _, node := range cluster.CacheNodes {
+		result.Metadata["nodes"] = append(result.Metadata["nodes"], fmt.Sprintf(
+			"%s %s",
+			aws.StringValue(node.CacheNodeId),
+			aws.StringValue(node.CacheNodeStatus),
+		))
+	}
+
+	result.Links["elasticache_console"] = elastiCacheConsoleLink(account.Region, engine, clusterID)
+
+	return result
+}
+
+func isElastiCacheHostname(query string) bool {
+	return isHostname(query) && strings.HasSuffix(normaliseHostname(query), ".cache.amazonaws.com")
+}
+
+func elastiCacheConsoleLink(region, engine, clusterID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/elasticache/home?region=%s#/%s/%s", region, engine, clusterID)
+}
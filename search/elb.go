@@ -0,0 +1,169 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+type elbSDK interface {
+	DescribeLoadBalancersPagesWithContext(ctx aws.Context, input *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool, opts ...request.Option) error
+}
+
+type elbv2SDK interface {
+	DescribeLoadBalancersPagesWithContext(ctx aws.Context, input *elbv2.DescribeLoadBalancersInput, fn func(*elbv2.DescribeLoadBalancersOutput, bool) bool, opts ...request.Option) error
+}
+
+// elbClient can discover both classic and application/network load
+// balancers in a specific account and region
+type elbClient struct {
+	classic elbSDK
+	v2      elbv2SDK
+	account Account
+}
+
+func buildElbClients(accounts []Account) []elbClient {
+	clients := []elbClient{}
+
+	for _, account := range accounts {
+		clients = append(clients, elbClient{
+			classic: elb.New(account.session, account.config),
+			v2:      elbv2.New(account.session, account.config),
+			account: account,
+		})
+	}
+
+	return clients
+}
+
+func NewElb(accounts []Account) *ELBResolver {
+	return &ELBResolver{clients: buildElbClients(accounts)}
+}
+
+// ELBResolver finds load balancers by the DNS name AWS assigned them
+type ELBResolver struct {
+	clients []elbClient
+}
+
+func (e *ELBResolver) Search(ctx context.Context, query string) []ResultSet {
+	if !isElbHostname(query) {
+		return []ResultSet{}
+	}
+
+	return []ResultSet{
+		{Kind: "elb.load_balancer", Results: e.findByDNSName(ctx, query)},
+	}
+}
+
+func (e *ELBResolver) findByDNSName(ctx context.Context, dnsName string) []Result {
+	dnsName = normaliseHostname(dnsName)
+	// Route53 aliases point at the dualstack name, but the API only
+	// reports the plain one
+	dnsName = strings.TrimPrefix(dnsName, "dualstack.")
+
+	results := []Result{}
+
+	for _, client := range e.clients {
+		// ELB DNS names always contain the region the load balancer lives in
+		if !inScope(ctx, client.account) || !strings.Contains(dnsName, "."+client.account.Region+".") {
+			continue
+		}
+
+		found, err := findLoadBalancersByDNSName(ctx, client, dnsName)
+		if err != nil {
+			log.Print(err)
+		}
+
+		results = append(results, found...)
+	}
+
+	return results
+}
+
+func findLoadBalancersByDNSName(ctx context.Context, client elbClient, dnsName string) ([]Result, error) {
+	results := []Result{}
+
+	// Neither API can filter by DNS name, so we have to list them all
+	err := client.v2.DescribeLoadBalancersPagesWithContext(
+		ctx,
+		&elbv2.DescribeLoadBalancersInput{},
+		func(page *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lb := range page.LoadBalancers {
+				if normaliseHostname(aws.StringValue(lb.DNSName)) != dnsName {
+					continue
+				}
+
+				result := newResult("elb.load_balancer", client.account)
+				result.Metadata["name"] = []string{aws.StringValue(lb.LoadBalancerName)}
+				result.Metadata["arn"] = []string{aws.StringValue(lb.LoadBalancerArn)}
+				result.Metadata["dns_name"] = []string{aws.StringValue(lb.DNSName)}
+				result.Metadata["type"] = []string{aws.StringValue(lb.Type)}
+				result.Metadata["scheme"] = []string{aws.StringValue(lb.Scheme)}
+				result.Metadata["vpc_id"] = []string{aws.StringValue(lb.VpcId)}
+				if lb.State != nil {
+					result.Metadata["state"] = []string{aws.StringValue(lb.State.Code)}
+				}
+				result.Links["ec2_console"] = loadBalancerConsoleLink(client.account.Region, aws.StringValue(lb.LoadBalancerName))
+
+				results = append(results, result)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	err = client.classic.DescribeLoadBalancersPagesWithContext(
+		ctx,
+		&elb.DescribeLoadBalancersInput{},
+		func(page *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lb := range page.LoadBalancerDescriptions {
+				if normaliseHostname(aws.StringValue(lb.DNSName)) != dnsName {
+					continue
+				}
+
+				instanceIDs := []string{}
+				for _, instance := range lb.Instances {
+					instanceIDs = append(instanceIDs, aws.StringValue(instance.InstanceId))
+				}
+
+				result := newResult("elb.load_balancer", client.account)
+				result.Metadata["name"] = []string{aws.StringValue(lb.LoadBalancerName)}
+				result.Metadata["dns_name"] = []string{aws.StringValue(lb.DNSName)}
+				result.Metadata["type"] = []string{"classic"}
+				result.Metadata["scheme"] = []string{aws.StringValue(lb.Scheme)}
+				result.Metadata["vpc_id"] = []string{aws.StringValue(lb.VPCId)}
+				result.Metadata["instance_ids"] = instanceIDs
+				result.Links["ec2_console"] = loadBalancerConsoleLink(client.account.Region, aws.StringValue(lb.LoadBalancerName))
+
+				results = append(results, result)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func isElbHostname(hostname string) bool {
+	hostname = normaliseHostname(hostname)
+
+	return strings.Contains(hostname, ".elb.") && strings.HasSuffix(hostname, ".amazonaws.com")
+}
+
+func loadBalancerConsoleLink(region, name string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/ec2/v2/home?region=%s#LoadBalancers:search=%s", region, name)
+}
@@ -0,0 +1,163 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InventoryMatchesToShow caps how many candidates are returned for a
+// partial or misspelt query
+const InventoryMatchesToShow = 10
+
+// MinPartialEc2InstanceIDLength stops very short prefixes like `i-0`
+// matching most of the inventory
+const MinPartialEc2InstanceIDLength = 6
+
+// Match kinds, best first
+const (
+	matchExact = iota
+	matchPrefix
+	matchFuzzy
+)
+
+type inventoryMatch struct {
+	resource Result
+	snapshot *InventorySnapshot
+	kind     int
+	distance int
+}
+
+// matchInstances finds instances whose ID starts with query, or whose name
+// is close to it, e.g. `i-0a1b2` or `web-prd-3`. Candidates are ranked
+// exact matches first, then prefixes, then by edit distance.
+func (i *Inventory) matchInstances(ctx context.Context, query string) *ResultSet {
+	if i == nil {
+		return nil
+	}
+
+	query = strings.ToLower(query)
+	partialID := strings.HasPrefix(query, "i-") && len(query) >= MinPartialEc2InstanceIDLength && len(query) < ExactEc2InstanceIDLength
+
+	i.mu.RLock()
+	matches := []inventoryMatch{}
+	for _, snapshot := range i.snapshots {
+		if !inScope(ctx, Account{ID: snapshot.AccountID, Region: snapshot.Region}) {
+			continue
+		}
+
+		for _, resource := range snapshot.Resources {
+			candidate := resource.GetMetadata("tag:Name")
+			if partialID {
+				candidate = resource.GetMetadata("instance_id")
+			}
+
+			if match, ok := matchName(query, strings.ToLower(candidate), !partialID); ok {
+				match.resource = resource
+				match.snapshot = snapshot
+				matches = append(matches, match)
+			}
+		}
+	}
+	i.mu.RUnlock()
+
+	if len(matches) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].kind != matches[b].kind {
+			return matches[a].kind < matches[b].kind
+		}
+		return matches[a].distance < matches[b].distance
+	})
+
+	if len(matches) > InventoryMatchesToShow {
+		matches = matches[:InventoryMatchesToShow]
+	}
+
+	results := []Result{}
+	for _, match := range matches {
+		result := match.snapshot.withFreshness(match.resource)
+
+		switch match.kind {
+		case matchExact:
+			result.Metadata["match"] = []string{"exact"}
+		case matchPrefix:
+			result.Metadata["match"] = []string{"prefix"}
+		default:
+			result.Metadata["match"] = []string{"distance " + strconv.Itoa(match.distance)}
+		}
+
+		results = append(results, result)
+	}
+
+	return &ResultSet{Kind: "ec2.instance_matches", Results: results}
+}
+
+// matchName compares a query with a candidate. Typos are only tolerated if
+// fuzzy is true, and only up to a third of the query's length, so that
+// short queries don't match everything.
+func matchName(query, candidate string, fuzzy bool) (inventoryMatch, bool) {
+	switch {
+	case candidate == "":
+		return inventoryMatch{}, false
+	case candidate == query:
+		return inventoryMatch{kind: matchExact}, true
+	case strings.HasPrefix(candidate, query):
+		return inventoryMatch{kind: matchPrefix, distance: len(candidate) - len(query)}, true
+	case !fuzzy:
+		return inventoryMatch{}, false
+	}
+
+	maxDistance := len(query) / 3
+	if maxDistance > 3 {
+		maxDistance = 3
+	}
+
+	distance := editDistance(query, candidate)
+	if distance > maxDistance {
+		return inventoryMatch{}, false
+	}
+
+	return inventoryMatch{kind: matchFuzzy, distance: distance}, true
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
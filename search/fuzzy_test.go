@@ -0,0 +1,56 @@
+package search
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"web", "", 3},
+		{"", "web", 3},
+		{"web-prd-3", "web-prd-3", 0},
+		{"web-prd-3", "web-prod-3", 1},
+		{"web-prd-3", "wbe-prd-3", 2},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, c := range cases {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Errorf("expected the distance between %q and %q to be %d, got %d", c.a, c.b, c.want, got)
+		}
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		candidate string
+		fuzzy     bool
+		ok        bool
+		kind      int
+		distance  int
+	}{
+		{"Exact", "web-prd-3", "web-prd-3", false, true, matchExact, 0},
+		{"Prefix", "web-prd", "web-prd-3", false, true, matchPrefix, 2},
+		{"Typo", "web-prod-3", "web-prd-3", true, true, matchFuzzy, 1},
+		{"Typos aren't tolerated unless fuzzy", "web-prod-3", "web-prd-3", false, false, 0, 0},
+		{"Too many typos", "wbe-prod-3", "web-prd-4", true, false, 0, 0},
+		{"Short queries only tolerate one typo", "wbe", "web", true, false, 0, 0},
+		{"Untagged instances never match", "web", "", true, false, 0, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			match, ok := matchName(c.query, c.candidate, c.fuzzy)
+			if ok != c.ok {
+				t.Fatalf("expected ok to be %v, got %v", c.ok, ok)
+			}
+			if ok && (match.kind != c.kind || match.distance != c.distance) {
+				t.Errorf("expected kind %d and distance %d, got %d and %d", c.kind, c.distance, match.kind, match.distance)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// asOfTimeFormats are the absolute times `as-of:` understands, all in UTC
+var asOfTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func NewHistory(store *SnapshotStore) *HistoryResolver {
+	return &HistoryResolver{store: store}
+}
+
+// HistoryResolver answers questions about the past from inventory
+// snapshots, e.g. `as-of:2019-03-01T14:00 10.0.1.23` finds the instance
+// that had that IP at the time, even if it has since been terminated.
+// Times can also be relative, e.g. `as-of:24h i-0123...`.
+type HistoryResolver struct {
+	store *SnapshotStore
+}
+
+func (h *HistoryResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	when, query, ok := parseAsOfQuery(query, time.Now())
+	if !ok || !(isEc2InstanceID(query) || isIPv4Address(query)) {
+		return results
+	}
+
+	aliases, err := h.store.Aliases()
+	if err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+		return results
+	}
+
+	found := []Result{}
+	for _, alias := range aliases {
+		snapshot, err := h.store.At(alias, when)
+		if err != nil {
+			bugsnag.Notify(err)
+			log.Print(err)
+			continue
+		}
+
+		if snapshot == nil || !inScope(ctx, Account{ID: snapshot.AccountID, Region: snapshot.Region}) {
+			continue
+		}
+
+		found = append(found, snapshot.find(query)...)
+	}
+
+	if len(found) > 0 {
+		results = append(results, ResultSet{Kind: "ec2.instance", Results: found})
+	}
+
+	return results
+}
+
+// parseAsOfQuery splits `as-of:<when> <query>` into the time and the query
+func parseAsOfQuery(query string, now time.Time) (time.Time, string, bool) {
+	rest, ok := trimQueryPrefix(query, "as-of:")
+	if !ok {
+		return time.Time{}, query, false
+	}
+
+	parts := strings.Fields(rest)
+	if len(parts) != 2 {
+		return time.Time{}, query, false
+	}
+
+	when, err := parseAsOfTime(parts[0], now)
+	if err != nil {
+		return time.Time{}, query, false
+	}
+
+	return when, parts[1], true
+}
+
+// parseAsOfTime understands absolute times, e.g. `2019-03-01T14:00`, and
+// durations, e.g. `36h`, which mean that long before now
+func parseAsOfTime(setting string, now time.Time) (time.Time, error) {
+	if ago, err := time.ParseDuration(setting); err == nil {
+		return now.Add(-ago), nil
+	}
+
+	for _, format := range asOfTimeFormats {
+		if when, err := time.Parse(format, setting); err == nil {
+			return when, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("could not parse as-of time %q", setting)
+}
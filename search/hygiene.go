@@ -0,0 +1,174 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StoppedInstanceMaxAge is how long an instance can be stopped for before
+// the hygiene report asks whether it's still needed
+const StoppedInstanceMaxAge = 14 * 24 * time.Hour
+
+// RequiredInstanceTags are the tags every instance should have when there's
+// no tag policy file, and which FormatEc2InstanceAsAttachment shows
+var RequiredInstanceTags = []string{"Environment", "Role"}
+
+// Checks that hygiene reports can include
+const (
+	HygieneUntaggedInstances     = "untagged"
+	HygieneStoppedInstances      = "stopped"
+	HygieneUnattachedVolumes     = "volumes"
+	HygieneUnassociatedAddresses = "addresses"
+	HygieneExpiringCertificates  = "certs"
+)
+
+// HygieneChecks lists every check, in the order they're reported
+var HygieneChecks = []string{
+	HygieneUntaggedInstances,
+	HygieneStoppedInstances,
+	HygieneUnattachedVolumes,
+	HygieneUnassociatedAddresses,
+	HygieneExpiringCertificates,
+}
+
+func NewHygiene(inventory *Inventory, acm *ACMResolver, policy *TagPolicy) *HygieneResolver {
+	return &HygieneResolver{inventory: inventory, acm: acm, policy: policy}
+}
+
+// HygieneResolver reports on things that are costing money or making
+// resources hard to find, e.g. `hygiene` or `hygiene stopped volumes`.
+// Everything but certificates comes from the inventory, so reports are
+// cheap to run on a schedule. Instances are checked against the same tag
+// policy as `compliance`.
+type HygieneResolver struct {
+	inventory *Inventory
+	acm       *ACMResolver
+	policy    *TagPolicy
+}
+
+func (h *HygieneResolver) ReportKeyword() string {
+	return "hygiene"
+}
+
+func (h *HygieneResolver) Search(ctx context.Context, query string) []ResultSet {
+	fields := strings.Fields(strings.ToLower(query))
+	if len(fields) == 0 || fields[0] != "hygiene" {
+		return []ResultSet{}
+	}
+
+	checks := fields[1:]
+	for _, check := range checks {
+		if !containsString(HygieneChecks, check) {
+			return []ResultSet{}
+		}
+	}
+
+	return h.Report(ctx, checks)
+}
+
+// Report runs each of the checks, or all of them if none are given. Each
+// check returns one result set, even if it found nothing. Until the
+// inventory has been crawled, the checks that use it are replaced by a
+// single hygiene.inventory_not_ready set, as finding nothing isn't the
+// same as there being nothing to find.
+func (h *HygieneResolver) Report(ctx context.Context, checks []string) []ResultSet {
+	if len(checks) == 0 {
+		checks = HygieneChecks
+	}
+
+	snapshots := []*InventorySnapshot{}
+	for _, snapshot := range h.inventory.allSnapshots() {
+		if inScope(ctx, Account{ID: snapshot.AccountID, Region: snapshot.Region}) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Alias < snapshots[j].Alias
+	})
+
+	sets := []ResultSet{}
+	for _, check := range HygieneChecks {
+		if !containsString(checks, check) {
+			continue
+		}
+
+		if len(snapshots) == 0 && check != HygieneExpiringCertificates {
+			if len(sets) == 0 || sets[len(sets)-1].Kind != "hygiene.inventory_not_ready" {
+				sets = append(sets, ResultSet{Kind: "hygiene.inventory_not_ready", Results: []Result{}})
+			}
+			continue
+		}
+
+		switch check {
+		case HygieneUntaggedInstances:
+			untagged := filterSnapshots(snapshots, h.breaksPolicy)
+			for _, instance := range untagged {
+				instance.Metadata["policy_violations"] = h.policy.violations(instance)
+			}
+			sets = append(sets, ResultSet{Kind: "hygiene.untagged_instances", Results: untagged})
+		case HygieneStoppedInstances:
+			sets = append(sets, ResultSet{Kind: "hygiene.stopped_instances", Results: filterSnapshots(snapshots, isStoppedTooLong)})
+		case HygieneUnattachedVolumes:
+			sets = append(sets, ResultSet{Kind: "hygiene.unattached_volumes", Results: collectSnapshots(snapshots, func(s *InventorySnapshot) []Result { return s.UnattachedVolumes })})
+		case HygieneUnassociatedAddresses:
+			addresses := collectSnapshots(snapshots, func(s *InventorySnapshot) []Result { return s.Addresses })
+			unassociated := []Result{}
+			for _, address := range addresses {
+				if address.GetMetadata("associated") == "false" {
+					unassociated = append(unassociated, address)
+				}
+			}
+			sets = append(sets, ResultSet{Kind: "hygiene.unassociated_addresses", Results: unassociated})
+		case HygieneExpiringCertificates:
+			if h.acm != nil {
+				sets = append(sets, h.acm.expiryReport(ctx, time.Duration(DefaultCertificateExpiryWindowDays)*24*time.Hour))
+			}
+		}
+	}
+
+	return sets
+}
+
+// filterSnapshots returns the instances in every snapshot that match
+func filterSnapshots(snapshots []*InventorySnapshot, match func(Result) bool) []Result {
+	results := []Result{}
+
+	for _, snapshot := range snapshots {
+		for _, instance := range snapshot.Resources {
+			if match(instance) {
+				results = append(results, snapshot.withFreshness(instance))
+			}
+		}
+	}
+
+	return results
+}
+
+// collectSnapshots returns the resources that resources picks out of every
+// snapshot
+func collectSnapshots(snapshots []*InventorySnapshot, resources func(*InventorySnapshot) []Result) []Result {
+	results := []Result{}
+
+	for _, snapshot := range snapshots {
+		for _, resource := range resources(snapshot) {
+			results = append(results, snapshot.withFreshness(resource))
+		}
+	}
+
+	return results
+}
+
+func (h *HygieneResolver) breaksPolicy(instance Result) bool {
+	return !isTerminated(instance) && len(h.policy.violations(instance)) > 0
+}
+
+func isStoppedTooLong(instance Result) bool {
+	stoppedAt, err := time.Parse(time.RFC3339, instance.GetMetadata("stopped_at"))
+	if err != nil {
+		return false
+	}
+
+	return time.Since(stoppedAt) > StoppedInstanceMaxAge
+}
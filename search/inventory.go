@@ -0,0 +1,330 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// DefaultInventoryRefreshInterval is how often the inventory is rebuilt
+// when no interval is configured
+const DefaultInventoryRefreshInterval = 5 * time.Minute
+
+// InventorySnapshot is every resource that was found in one account and
+// region at a point in time
+type InventorySnapshot struct {
+	Alias     string
+	AccountID string
+	Region    string
+	TakenAt   time.Time
+	// Resources are the account's EC2 instances
+	Resources []Result
+	// UnattachedVolumes and Addresses are kept for hygiene reports
+	UnattachedVolumes []Result
+	Addresses         []Result
+
+	byID map[string]Result
+	byIP map[string][]Result
+}
+
+// index builds the lookup tables for the snapshot's resources
+func (s *InventorySnapshot) index() {
+	s.byID = map[string]Result{}
+	s.byIP = map[string][]Result{}
+
+	for _, resource := range s.Resources {
+		if id := resource.GetMetadata("instance_id"); id != "" {
+			s.byID[id] = resource
+		}
+
+		for _, ip := range append(resource.Metadata["private_ips"], resource.Metadata["public_ips"]...) {
+			s.byIP[ip] = append(s.byIP[ip], resource)
+		}
+	}
+}
+
+// Inventory periodically crawls every account, so that searches can be
+// answered without calling AWS, and so that we can search in ways the AWS
+// APIs don't support
+type Inventory struct {
+	clients []ec2Client
+	store   *SnapshotStore
+
+	mu        sync.RWMutex
+	snapshots map[string]*InventorySnapshot
+	listeners []func(before, after *InventorySnapshot)
+}
+
+// NewInventory creates an inventory of accounts. Snapshots are also saved
+// to store, if one is given.
+func NewInventory(accounts []Account, store *SnapshotStore) *Inventory {
+	return &Inventory{
+		clients:   buildEc2Clients(accounts),
+		store:     store,
+		snapshots: map[string]*InventorySnapshot{},
+	}
+}
+
+// OnRefresh calls fn every time an account is crawled, with the account's
+// previous snapshot (which is nil the first time) and its new one
+func (i *Inventory) OnRefresh(fn func(before, after *InventorySnapshot)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.listeners = append(i.listeners, fn)
+}
+
+// Run refreshes the inventory straight away, and then every interval until
+// ctx is cancelled
+func (i *Inventory) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		i.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh crawls every account in parallel. Accounts that can't be crawled
+// keep their previous snapshot.
+func (i *Inventory) Refresh(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, client := range i.clients {
+		wg.Add(1)
+		go func(client ec2Client) {
+			defer wg.Done()
+
+			snapshot, err := crawlInventory(ctx, client)
+			if err != nil {
+				log.Print(err)
+				return
+			}
+
+			if i.store != nil {
+				if err := i.store.Save(snapshot); err != nil {
+					bugsnag.Notify(err)
+					log.Print(err)
+				}
+			}
+
+			i.mu.Lock()
+			before := i.snapshots[client.account.Alias]
+			i.snapshots[client.account.Alias] = snapshot
+			listeners := i.listeners
+			i.mu.Unlock()
+
+			for _, listener := range listeners {
+				listener(before, snapshot)
+			}
+		}(client)
+	}
+
+	wg.Wait()
+
+	if i.store != nil {
+		if err := i.store.Prune(time.Now()); err != nil {
+			bugsnag.Notify(err)
+			log.Print(err)
+		}
+	}
+}
+
+func crawlInventory(ctx context.Context, client ec2Client) (*InventorySnapshot, error) {
+	snapshot := &InventorySnapshot{
+		Alias:             client.account.Alias,
+		AccountID:         client.account.ID,
+		Region:            client.account.Region,
+		TakenAt:           time.Now().UTC(),
+		Resources:         []Result{},
+		UnattachedVolumes: []Result{},
+		Addresses:         []Result{},
+	}
+
+	err := client.DescribeInstancesPagesWithContext(
+		ctx,
+		&ec2.DescribeInstancesInput{MaxResults: aws.Int64(1000)},
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					snapshot.Resources = append(snapshot.Resources, ec2InstanceToResult(client.account, instance))
+				}
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	// Status checks are nice to have, so the snapshot is still kept if
+	// they can't be fetched
+	if err := addStatusChecks(ctx, client, snapshot.Resources); err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+	}
+
+	// As are the resources that hygiene reports look at
+	if err := crawlUnattachedVolumes(ctx, client, snapshot); err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+	}
+	if err := crawlAddresses(ctx, client, snapshot); err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+	}
+
+	snapshot.index()
+
+	return snapshot, nil
+}
+
+func crawlUnattachedVolumes(ctx context.Context, client ec2Client, snapshot *InventorySnapshot) error {
+	return client.DescribeVolumesPagesWithContext(
+		ctx,
+		&ec2.DescribeVolumesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{Name: aws.String("status"), Values: aws.StringSlice([]string{ec2.VolumeStateAvailable})},
+			},
+		},
+		func(page *ec2.DescribeVolumesOutput, lastPage bool) bool {
+			for _, volume := range page.Volumes {
+				snapshot.UnattachedVolumes = append(snapshot.UnattachedVolumes, ebsVolumeToResult(client.account, volume))
+			}
+			return true
+		},
+	)
+}
+
+func crawlAddresses(ctx context.Context, client ec2Client, snapshot *InventorySnapshot) error {
+	output, err := client.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return err
+	}
+
+	for _, address := range output.Addresses {
+		snapshot.Addresses = append(snapshot.Addresses, elasticIPToResult(client.account, address))
+	}
+
+	return nil
+}
+
+// addStatusChecks records the system and instance status checks of every
+// running instance, e.g. `ok` or `impaired`
+func addStatusChecks(ctx context.Context, client ec2Client, instances []Result) error {
+	byID := map[string]Result{}
+	for _, instance := range instances {
+		byID[instance.GetMetadata("instance_id")] = instance
+	}
+
+	return client.DescribeInstanceStatusPagesWithContext(
+		ctx,
+		&ec2.DescribeInstanceStatusInput{MaxResults: aws.Int64(1000)},
+		func(page *ec2.DescribeInstanceStatusOutput, lastPage bool) bool {
+			for _, status := range page.InstanceStatuses {
+				instance, ok := byID[aws.StringValue(status.InstanceId)]
+				if !ok {
+					continue
+				}
+
+				if status.SystemStatus != nil {
+					instance.Metadata["system_status"] = []string{aws.StringValue(status.SystemStatus.Status)}
+				}
+				if status.InstanceStatus != nil {
+					instance.Metadata["instance_status"] = []string{aws.StringValue(status.InstanceStatus.Status)}
+				}
+			}
+			return true
+		},
+	)
+}
+
+// allSnapshots returns the latest snapshot of every account that's been
+// crawled. It's safe to call on a nil Inventory.
+func (i *Inventory) allSnapshots() []*InventorySnapshot {
+	snapshots := []*InventorySnapshot{}
+	if i == nil {
+		return snapshots
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, snapshot := range i.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots
+}
+
+// snapshot returns the latest snapshot of account, or nil if it hasn't
+// been crawled yet. It's safe to call on a nil Inventory.
+func (i *Inventory) snapshot(account Account) *InventorySnapshot {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.snapshots[account.Alias]
+}
+
+// findInstances looks up an instance ID or IP in the account's latest
+// snapshot. It returns nil if the instance isn't in the snapshot, so that
+// the caller can fall back to asking AWS, as it may have been launched
+// since the snapshot was taken.
+func (i *Inventory) findInstances(account Account, query string) *ResultSet {
+	snapshot := i.snapshot(account)
+	if snapshot == nil {
+		return nil
+	}
+
+	results := snapshot.find(query)
+	if len(results) == 0 {
+		return nil
+	}
+
+	return &ResultSet{Kind: "ec2.instance", Results: results}
+}
+
+// find looks up an instance ID or IP in the snapshot
+func (s *InventorySnapshot) find(query string) []Result {
+	found := []Result{}
+	if isEc2InstanceID(query) {
+		if instance, ok := s.byID[query]; ok {
+			found = append(found, instance)
+		}
+	} else if isIPv4Address(query) {
+		found = append(found, s.byIP[query]...)
+	}
+
+	results := []Result{}
+	for _, instance := range found {
+		results = append(results, s.withFreshness(instance))
+	}
+
+	return results
+}
+
+// withFreshness copies a resource out of the snapshot, noting when the
+// snapshot was taken. The copy stops annotations on search results from
+// changing the snapshot.
+func (s *InventorySnapshot) withFreshness(resource Result) Result {
+	result := resource.copy()
+	result.Metadata["indexed_at"] = []string{s.TakenAt.Format(time.RFC3339)}
+
+	return result
+}
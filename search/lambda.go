@@ -0,0 +1,178 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// LambdaVersionsToShow is how many of a function's most recently
+// published versions are included in its result
+const LambdaVersionsToShow = 5
+
+type lambdaSDK interface {
+	GetFunctionConfigurationWithContext(ctx aws.Context, input *lambda.GetFunctionConfigurationInput, opts ...request.Option) (*lambda.FunctionConfiguration, error)
+	ListAliasesWithContext(ctx aws.Context, input *lambda.ListAliasesInput, opts ...request.Option) (*lambda.ListAliasesOutput, error)
+	ListVersionsByFunctionWithContext(ctx aws.Context, input *lambda.ListVersionsByFunctionInput, opts ...request.Option) (*lambda.ListVersionsByFunctionOutput, error)
+}
+
+type lambdaClient struct {
+	lambdaSDK
+	account Account
+}
+
+func buildLambdaClients(accounts []Account) []lambdaClient {
+	clients := []lambdaClient{}
+
+	for _, account := range accounts {
+		svc := lambda.New(account.session, account.config)
+
+		clients = append(clients, lambdaClient{lambdaSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+func NewLambda(accounts []Account) *LambdaResolver {
+	return &LambdaResolver{clients: buildLambdaClients(accounts)}
+}
+
+// LambdaResolver finds lambda functions by name, e.g. `fn:payments-webhook`,
+// or by their ARN
+type LambdaResolver struct {
+	clients []lambdaClient
+}
+
+func (l *LambdaResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	name, ok := trimQueryPrefix(query, "fn:")
+	if arn, isArn := parseArn(query); !ok && !(isArn && arn.service == "lambda") {
+		return results
+	}
+
+	for _, client := range l.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		result, err := findLambdaFunction(ctx, client, name)
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func findLambdaFunction(ctx context.Context, client lambdaClient, name string) (*ResultSet, error) {
+	function, err := client.GetFunctionConfigurationWithContext(
+		ctx,
+		&lambda.GetFunctionConfigurationInput{FunctionName: aws.String(name)},
+	)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == lambda.ErrCodeResourceNotFoundException {
+			return nil, nil
+		}
+
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	result := lambdaFunctionToResult(client.account, function)
+
+	aliases, err := client.ListAliasesWithContext(
+		ctx,
+		&lambda.ListAliasesInput{FunctionName: function.FunctionName},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	aliasDescriptions := []string{}
+	for _, alias := range aliases.Aliases {
+		aliasDescriptions = append(aliasDescriptions, fmt.Sprintf(
+			"%s → %s",
+			aws.StringValue(alias.Name),
+			aws.StringValue(alias.FunctionVersion),
+		))
+	}
+	result.Metadata["aliases"] = aliasDescriptions
+
+	// The SDK doesn't have a paginator for ListVersionsByFunction
+	versions := []string{}
+	input := &lambda.ListVersionsByFunctionInput{FunctionName: function.FunctionName}
+	for {
+		page, err := client.ListVersionsByFunctionWithContext(ctx, input)
+		if err != nil {
+			bugsnag.Notify(err)
+			return nil, err
+		}
+
+		for _, version := range page.Versions {
+			if aws.StringValue(version.Version) == "$LATEST" {
+				continue
+			}
+			versions = append(versions, aws.StringValue(version.Version))
+		}
+
+		if page.NextMarker == nil {
+			break
+		}
+		input.Marker = page.NextMarker
+	}
+
+	// Versions are listed oldest first
+	if len(versions) > LambdaVersionsToShow {
+		versions = versions[len(versions)-LambdaVersionsToShow:]
+	}
+	result.Metadata["versions"] = versions
+
+	return &ResultSet{Kind: "lambda.function", Results: []Result{result}}, nil
+}
+
+func lambdaFunctionToResult(account Account, function *lambda.FunctionConfiguration) Result {
+	name := aws.StringValue(function.FunctionName)
+
+	result := newResult("lambda.function", account)
+	result.Metadata["name"] = []string{name}
+	result.Metadata["arn"] = []string{aws.StringValue(function.FunctionArn)}
+	result.Metadata["runtime"] = []string{aws.StringValue(function.Runtime)}
+	result.Metadata["handler"] = []string{aws.StringValue(function.Handler)}
+	result.Metadata["memory_size"] = []string{strconv.FormatInt(aws.Int64Value(function.MemorySize), 10)}
+	result.Metadata["timeout"] = []string{strconv.FormatInt(aws.Int64Value(function.Timeout), 10)}
+	result.Metadata["last_modified"] = []string{aws.StringValue(function.LastModified)}
+	result.Metadata["code_sha256"] = []string{aws.StringValue(function.CodeSha256)}
+
+	if function.VpcConfig != nil && aws.StringValue(function.VpcConfig.VpcId) != "" {
+		result.Metadata["vpc_id"] = []string{aws.StringValue(function.VpcConfig.VpcId)}
+		result.Metadata["subnet_ids"] = aws.StringValueSlice(function.VpcConfig.SubnetIds)
+		result.Metadata["security_group_ids"] = aws.StringValueSlice(function.VpcConfig.SecurityGroupIds)
+	}
+
+	result.Links["lambda_console"] = lambdaConsoleLink(account.Region, name)
+	result.Links["logs"] = lambdaLogsLink(account.Region, name)
+
+	return result
+}
+
+func lambdaConsoleLink(region, name string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/lambda/home?region=%s#/functions/%s", region, name)
+}
+
+// lambdaLogsLink links to the log group lambda creates for each function
+func lambdaLogsLink(region, name string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/cloudwatch/home?region=%s#logStream:group=/aws/lambda/%s", region, name)
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// linkPlaceholderPattern finds the metadata keys in a link template, e.g.
+// `{instance_id}` or `{tag:Role}`
+var linkPlaceholderPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// builtinLinkNames are the keys resolvers use in Result.Links. Link
+// templates can't use them, as they'd replace the links that formatters
+// and result templates expect.
+var builtinLinkNames = []string{
+	"acm_console",
+	"autoscaling_console",
+	"autoscaling_group",
+	"cloudformation_console",
+	"cloudformation_stack",
+	"config_timeline",
+	"dynamodb_console",
+	"ec2_console",
+	"ecs_console",
+	"elasticache_console",
+	"instance",
+	"lambda_console",
+	"logs",
+	"nat_gateway",
+	"network_interface",
+	"route53_console",
+	"s3_console",
+	"sns_console",
+	"source_snapshot",
+	"source_volume",
+	"sqs_console",
+	"vpc_console",
+}
+
+// LinkTemplate adds a link to another tool, like a dashboard or runbook,
+// to results. Placeholders in the URL are replaced with the result's
+// metadata, e.g.
+//
+//	https://app.datadoghq.com/infrastructure?filter=host:{instance_id}
+//
+// Results that don't have all of the metadata don't get the link.
+type LinkTemplate struct {
+	// Name labels the link's button, and is its key in Result.Links
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Kinds limits the link to some kinds of result, e.g. `ec2.instance`
+	Kinds []string `json:"kinds,omitempty"`
+}
+
+// LoadLinkTemplates reads a list of link templates from a JSON file, e.g.
+//
+//	[
+//	  {"name": "Datadog", "url": "https://app.datadoghq.com/infrastructure?filter=host:{instance_id}"},
+//	  {"name": "Runbook", "url": "https://wiki.example.com/runbooks/{tag:Role}", "kinds": ["ec2.instance"]}
+//	]
+func LoadLinkTemplates(path string) ([]LinkTemplate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []LinkTemplate{}
+	if err := json.Unmarshal(b, &templates); err != nil {
+		return nil, fmt.Errorf("could not read link templates from %s: %s", path, err)
+	}
+
+	for i, template := range templates {
+		if template.Name == "" || template.URL == "" {
+			return nil, fmt.Errorf("link template %d in %s needs a name and a url", i+1, path)
+		}
+
+		if containsString(builtinLinkNames, template.Name) {
+			return nil, fmt.Errorf("link template %d in %s can't be called %s, as that's one of slash-infra's own links", i+1, path, template.Name)
+		}
+	}
+
+	return templates, nil
+}
+
+// expand fills in the template's placeholders from result's metadata. It's
+// false if the template doesn't apply to the result, or the result is
+// missing some of the metadata.
+func (t LinkTemplate) expand(result Result) (string, bool) {
+	if len(t.Kinds) > 0 && !containsString(t.Kinds, result.Kind) {
+		return "", false
+	}
+
+	complete := true
+	link := linkPlaceholderPattern.ReplaceAllStringFunc(t.URL, func(placeholder string) string {
+		value := result.GetMetadata(strings.Trim(placeholder, "{}"))
+		if value == "" {
+			complete = false
+		}
+
+		// QueryEscape turns spaces into `+`, which isn't a space in a path
+		return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+	})
+
+	return link, complete
+}
+
+func NewLinks(templates []LinkTemplate) *LinkResolver {
+	return &LinkResolver{templates: templates}
+}
+
+// LinkResolver doesn't find anything itself, but adds configured links to
+// other tools to every result. The names of the links it added are listed
+// in the result's `external_links` metadata, in the order they were
+// configured.
+type LinkResolver struct {
+	templates []LinkTemplate
+}
+
+func (l *LinkResolver) Search(ctx context.Context, query string) []ResultSet {
+	return []ResultSet{}
+}
+
+func (l *LinkResolver) Annotate(ctx context.Context, sets []ResultSet) {
+	for _, set := range sets {
+		for _, result := range set.Results {
+			for _, template := range l.templates {
+				link, ok := template.expand(result)
+				// Results can appear in more than one set, e.g. in a DNS chain
+				// and on their own
+				if !ok || result.Links[template.Name] == link {
+					continue
+				}
+
+				result.Links[template.Name] = link
+				result.Metadata["external_links"] = append(result.Metadata["external_links"], template.Name)
+			}
+		}
+	}
+}
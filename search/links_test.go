@@ -0,0 +1,97 @@
+package search
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkTemplateExpand(t *testing.T) {
+	instance := Result{
+		Kind: "ec2.instance",
+		Metadata: map[string][]string{
+			"instance_id": []string{"i-0123456789abcdef0"},
+			"tag:Role":    []string{"web server"},
+			"tag:Query":   []string{"a&b=c"},
+		},
+		Links: map[string]string{},
+	}
+
+	cases := []struct {
+		name     string
+		template LinkTemplate
+		want     string
+		ok       bool
+	}{
+		{
+			name:     "Metadata",
+			template: LinkTemplate{URL: "https://app.datadoghq.com/infrastructure?filter=host:{instance_id}"},
+			want:     "https://app.datadoghq.com/infrastructure?filter=host:i-0123456789abcdef0",
+			ok:       true,
+		},
+		{
+			name:     "Spaces in a path",
+			template: LinkTemplate{URL: "https://wiki.example.com/runbooks/{tag:Role}"},
+			want:     "https://wiki.example.com/runbooks/web%20server",
+			ok:       true,
+		},
+		{
+			name:     "Values are escaped",
+			template: LinkTemplate{URL: "https://example.com/?q={tag:Query}"},
+			want:     "https://example.com/?q=a%26b%3Dc",
+			ok:       true,
+		},
+		{
+			name:     "No placeholders",
+			template: LinkTemplate{URL: "https://status.example.com"},
+			want:     "https://status.example.com",
+			ok:       true,
+		},
+		{
+			name:     "Missing metadata",
+			template: LinkTemplate{URL: "https://example.com/{tag:Owner}"},
+			ok:       false,
+		},
+		{
+			name:     "Matching kind",
+			template: LinkTemplate{URL: "https://example.com/{instance_id}", Kinds: []string{"ec2.instance"}},
+			want:     "https://example.com/i-0123456789abcdef0",
+			ok:       true,
+		},
+		{
+			name:     "Other kinds",
+			template: LinkTemplate{URL: "https://example.com/{instance_id}", Kinds: []string{"ebs.volume"}},
+			ok:       false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			link, ok := c.template.expand(instance)
+			if ok != c.ok {
+				t.Fatalf("expected ok to be %v, got %v", c.ok, ok)
+			}
+			if ok && link != c.want {
+				t.Errorf("expected %q, got %q", c.want, link)
+			}
+		})
+	}
+}
+
+func TestLoadLinkTemplatesRejectsBuiltinNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "links")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "links.json")
+	if err := ioutil.WriteFile(path, []byte(`[{"name": "ec2_console", "url": "https://example.com/{instance_id}"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadLinkTemplates(path); err == nil {
+		t.Error("expected a link template called ec2_console to be rejected")
+	}
+}
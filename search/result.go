@@ -0,0 +1,63 @@
+package search
+
+import (
+	"strings"
+)
+
+type Result struct {
+	Kind     string
+	Metadata map[string][]string
+	Links    map[string]string
+}
+
+// newResult creates a Result for a resource that was found in account
+func newResult(kind string, account Account) Result {
+	return Result{
+		Kind:     kind,
+		Metadata: account.metadata(),
+		Links:    map[string]string{},
+	}
+}
+
+// copy returns a result whose metadata and links can be changed without
+// affecting r
+func (r Result) copy() Result {
+	c := Result{
+		Kind:     r.Kind,
+		Metadata: make(map[string][]string, len(r.Metadata)),
+		Links:    make(map[string]string, len(r.Links)),
+	}
+
+	for key, values := range r.Metadata {
+		c.Metadata[key] = values
+	}
+	for key, url := range r.Links {
+		c.Links[key] = url
+	}
+
+	return c
+}
+
+func (r Result) GetMetadata(key string) string {
+	set, ok := r.Metadata[key]
+	if !ok {
+		return ""
+	}
+
+	return strings.Join(set, ", ")
+}
+
+func (r Result) GetLink(key string) string {
+	url, ok := r.Links[key]
+	if !ok {
+		return ""
+	}
+
+	return url
+}
+
+type ResultSet struct {
+	Kind       string
+	SearchLink string
+	Results    []Result
+}
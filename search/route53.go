@@ -0,0 +1,319 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// MaxDNSChainDepth stops us following CNAMEs forever if someone has
+// managed to create a loop
+const MaxDNSChainDepth = 5
+
+type route53SDK interface {
+	ListHostedZonesPagesWithContext(ctx aws.Context, input *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool, opts ...request.Option) error
+	ListResourceRecordSetsWithContext(ctx aws.Context, input *route53.ListResourceRecordSetsInput, opts ...request.Option) (*route53.ListResourceRecordSetsOutput, error)
+}
+
+type route53Client struct {
+	route53SDK
+	account Account
+}
+
+func buildRoute53Clients(accounts []Account) []route53Client {
+	clients := []route53Client{}
+
+	// Route53 is a global service, there's no point searching the same
+	// account once for each region it's been configured for
+	for _, account := range uniqueAccounts(accounts) {
+		svc := route53.New(account.session, account.config)
+
+		clients = append(clients, route53Client{route53SDK: svc, account: account})
+	}
+
+	return clients
+}
+
+// NewRoute53 creates a resolver that maps hostnames to the resources
+// behind them. The EC2 and ELB resolvers are used to look up the targets
+// of DNS records.
+func NewRoute53(accounts []Account, ec2Resolver *EC2Resolver, elbResolver *ELBResolver) *Route53Resolver {
+	return &Route53Resolver{
+		clients: buildRoute53Clients(accounts),
+		ec2:     ec2Resolver,
+		elb:     elbResolver,
+	}
+}
+
+// Route53Resolver finds the record sets for a hostname in any of the
+// hosted zones slash-infra can see, and follows them to the resources
+// they point at, e.g.
+//
+//	CNAME -> ELB DNS name -> load balancer
+//	A -> IP -> EC2 instance/network interface
+//
+// Every hop in the chain is a Result in a "route53.chain" ResultSet. Each
+// has "resolver" metadata naming the resolver that found it, and "hop"
+// metadata recording how far down the chain it is.
+type Route53Resolver struct {
+	clients []route53Client
+	ec2     *EC2Resolver
+	elb     *ELBResolver
+}
+
+func (r *Route53Resolver) Search(ctx context.Context, query string) []ResultSet {
+	if !isHostname(query) {
+		return []ResultSet{}
+	}
+
+	hops := r.resolveChain(ctx, normaliseHostname(query), 0, map[string]bool{})
+	if len(hops) == 0 {
+		return []ResultSet{}
+	}
+
+	return []ResultSet{
+		{Kind: "route53.chain", Results: hops},
+	}
+}
+
+func (r *Route53Resolver) resolveChain(ctx context.Context, hostname string, depth int, visited map[string]bool) []Result {
+	if depth >= MaxDNSChainDepth || visited[hostname] {
+		return []Result{}
+	}
+	visited[hostname] = true
+
+	hops := []Result{}
+
+	for _, client := range r.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		records, err := findRecordSets(ctx, client, hostname)
+		if err != nil {
+			log.Print(err)
+		}
+
+		for _, record := range records {
+			hops = append(hops, withHop(record, "route53", depth))
+
+			for _, target := range record.Metadata["alias_target"] {
+				hops = append(hops, r.resolveHostname(ctx, target, depth+1, visited)...)
+			}
+
+			if record.GetMetadata("type") == route53.RRTypeCname {
+				for _, target := range record.Metadata["values"] {
+					hops = append(hops, r.resolveHostname(ctx, target, depth+1, visited)...)
+				}
+			}
+
+			if record.GetMetadata("type") == route53.RRTypeA && len(record.Metadata["alias_target"]) == 0 {
+				for _, ip := range record.Metadata["values"] {
+					hops = append(hops, r.resolveIP(ctx, ip, depth+1)...)
+				}
+			}
+		}
+	}
+
+	return hops
+}
+
+// resolveHostname works out which resolver owns the target of a CNAME or
+// alias record
+func (r *Route53Resolver) resolveHostname(ctx context.Context, hostname string, depth int, visited map[string]bool) []Result {
+	hostname = normaliseHostname(hostname)
+	hops := []Result{}
+
+	if isElbHostname(hostname) {
+		for _, lb := range r.elb.findByDNSName(ctx, hostname) {
+			hops = append(hops, withHop(lb, "elb", depth))
+		}
+	} else {
+		hops = r.resolveChain(ctx, hostname, depth, visited)
+	}
+
+	if len(hops) == 0 {
+		hops = append(hops, unresolvedHop(hostname, depth))
+	}
+
+	return hops
+}
+
+func (r *Route53Resolver) resolveIP(ctx context.Context, ip string, depth int) []Result {
+	hops := []Result{}
+
+	for _, resource := range r.ec2.findByIP(ctx, ip) {
+		hops = append(hops, withHop(resource, "ec2", depth))
+	}
+
+	if len(hops) == 0 {
+		hops = append(hops, unresolvedHop(ip, depth))
+	}
+
+	return hops
+}
+
+func findRecordSets(ctx context.Context, client route53Client, hostname string) ([]Result, error) {
+	zones := []*route53.HostedZone{}
+	longestMatch := 0
+
+	// Find the most specific zone(s) the hostname could be in. There may
+	// be more than one if the account has a public and private zone with
+	// the same name
+	err := client.ListHostedZonesPagesWithContext(
+		ctx,
+		&route53.ListHostedZonesInput{},
+		func(page *route53.ListHostedZonesOutput, lastPage bool) bool {
+			for _, zone := range page.HostedZones {
+				zoneName := normaliseHostname(aws.StringValue(zone.Name))
+
+				if hostname != zoneName && !strings.HasSuffix(hostname, "."+zoneName) {
+					continue
+				}
+
+				if len(zoneName) > longestMatch {
+					zones = []*route53.HostedZone{}
+					longestMatch = len(zoneName)
+				}
+				if len(zoneName) == longestMatch {
+					zones = append(zones, zone)
+				}
+			}
+			return true
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	results := []Result{}
+
+	for _, zone := range zones {
+		output, err := client.ListResourceRecordSetsWithContext(
+			ctx,
+			&route53.ListResourceRecordSetsInput{
+				HostedZoneId:    zone.Id,
+				StartRecordName: aws.String(hostname),
+				// Enough to cover weighted/latency based record sets
+				MaxItems: aws.String("20"),
+			},
+		)
+		if err != nil {
+			bugsnag.Notify(err)
+			return nil, err
+		}
+
+		for _, recordSet := range output.ResourceRecordSets {
+			if normaliseHostname(aws.StringValue(recordSet.Name)) != hostname {
+				continue
+			}
+
+			switch aws.StringValue(recordSet.Type) {
+			case route53.RRTypeA, route53.RRTypeAaaa, route53.RRTypeCname:
+			default:
+				continue
+			}
+
+			results = append(results, recordSetToResult(client.account, zone, recordSet))
+		}
+	}
+
+	return results, nil
+}
+
+func recordSetToResult(account Account, zone *route53.HostedZone, recordSet *route53.ResourceRecordSet) Result {
+	zoneID := strings.TrimPrefix(aws.StringValue(zone.Id), "/hostedzone/")
+
+	values := []string{}
+	for _, record := range recordSet.ResourceRecords {
+		values = append(values, aws.StringValue(record.Value))
+	}
+
+	private := false
+	if zone.Config != nil {
+		private = aws.BoolValue(zone.Config.PrivateZone)
+	}
+
+	result := newResult("route53.record", account)
+	result.Metadata["name"] = []string{normaliseHostname(aws.StringValue(recordSet.Name))}
+	result.Metadata["type"] = []string{aws.StringValue(recordSet.Type)}
+	result.Metadata["values"] = values
+	result.Metadata["zone_id"] = []string{zoneID}
+	result.Metadata["zone_name"] = []string{normaliseHostname(aws.StringValue(zone.Name))}
+	result.Metadata["private_zone"] = []string{strconv.FormatBool(private)}
+
+	if recordSet.AliasTarget != nil {
+		result.Metadata["alias_target"] = []string{normaliseHostname(aws.StringValue(recordSet.AliasTarget.DNSName))}
+	}
+	if recordSet.SetIdentifier != nil {
+		result.Metadata["set_identifier"] = []string{*recordSet.SetIdentifier}
+	}
+
+	result.Links["route53_console"] = route53ConsoleLink(zoneID)
+
+	return result
+}
+
+// withHop records where in a DNS chain a result was found, and by which
+// resolver
+func withHop(result Result, resolver string, depth int) Result {
+	result.Metadata["resolver"] = []string{resolver}
+	result.Metadata["hop"] = []string{strconv.Itoa(depth)}
+
+	return result
+}
+
+// unresolvedHop marks the point at which we could no longer follow a DNS
+// chain, e.g. because it points outside of AWS or at a service we can't
+// resolve
+func unresolvedHop(target string, depth int) Result {
+	return withHop(Result{
+		Kind:     "dns.unresolved",
+		Metadata: map[string][]string{"target": []string{target}},
+		Links:    map[string]string{},
+	}, "none", depth)
+}
+
+// normaliseHostname makes hostnames from different sources comparable.
+// Route53 returns fully qualified names with a trailing "." and escapes
+// wildcards.
+func normaliseHostname(hostname string) string {
+	hostname = strings.ToLower(strings.TrimSpace(hostname))
+	hostname = strings.TrimSuffix(hostname, ".")
+
+	return strings.Replace(hostname, `\052`, "*", -1)
+}
+
+func isHostname(query string) bool {
+	query = normaliseHostname(query)
+
+	if !strings.Contains(query, ".") || isIPv4Address(query) {
+		return false
+	}
+
+	for _, label := range strings.Split(query, ".") {
+		if label == "" {
+			return false
+		}
+
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '-' && c != '_' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func route53ConsoleLink(zoneID string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/route53/home#resource-record-sets:%s", zoneID)
+}
@@ -0,0 +1,238 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// Matches virtual hosted style S3 hostnames, e.g. my-bucket.s3.amazonaws.com
+// or my-bucket.s3.eu-west-2.amazonaws.com
+var bucketHostnamePattern = regexp.MustCompile(`^(.+)\.s3([.-][a-z0-9-]+)?\.amazonaws\.com$`)
+
+// s3SDK only uses read only calls. Each one needs the matching IAM
+// action in the role slash-infra assumes, see terraform/aws-app-role
+type s3SDK interface {
+	ListBucketsWithContext(ctx aws.Context, input *s3.ListBucketsInput, opts ...request.Option) (*s3.ListBucketsOutput, error)
+	GetBucketLocationWithContext(ctx aws.Context, input *s3.GetBucketLocationInput, opts ...request.Option) (*s3.GetBucketLocationOutput, error)
+	GetBucketVersioningWithContext(ctx aws.Context, input *s3.GetBucketVersioningInput, opts ...request.Option) (*s3.GetBucketVersioningOutput, error)
+	GetBucketEncryptionWithContext(ctx aws.Context, input *s3.GetBucketEncryptionInput, opts ...request.Option) (*s3.GetBucketEncryptionOutput, error)
+	GetPublicAccessBlockWithContext(ctx aws.Context, input *s3.GetPublicAccessBlockInput, opts ...request.Option) (*s3.GetPublicAccessBlockOutput, error)
+	GetBucketPolicyStatusWithContext(ctx aws.Context, input *s3.GetBucketPolicyStatusInput, opts ...request.Option) (*s3.GetBucketPolicyStatusOutput, error)
+	GetBucketTaggingWithContext(ctx aws.Context, input *s3.GetBucketTaggingInput, opts ...request.Option) (*s3.GetBucketTaggingOutput, error)
+}
+
+type s3Client struct {
+	s3SDK
+	account Account
+
+	// S3 redirects requests for a bucket's configuration to the region
+	// the bucket is in, so we need a client for that region
+	inRegion func(region string) s3SDK
+}
+
+func buildS3Clients(accounts []Account) []s3Client {
+	clients := []s3Client{}
+
+	// Buckets are listed globally, so each account only needs searching once
+	for _, account := range uniqueAccounts(accounts) {
+		account := account
+
+		clients = append(clients, s3Client{
+			s3SDK:   s3.New(account.session, account.config),
+			account: account,
+			inRegion: func(region string) s3SDK {
+				return s3.New(account.session, account.config.Copy(&aws.Config{Region: aws.String(region)}))
+			},
+		})
+	}
+
+	return clients
+}
+
+func NewS3(accounts []Account) *S3Resolver {
+	return &S3Resolver{clients: buildS3Clients(accounts)}
+}
+
+// S3Resolver works out which of the configured accounts owns a bucket,
+// and reports how the bucket is configured. Buckets can be searched for
+// by name prefixed with `s3:` or `s3://`, ARN, or their virtual hosted
+// style hostname. Bare names aren't searched for, as nearly every query
+// looks like one and ListBuckets would be called in every account.
+type S3Resolver struct {
+	clients []s3Client
+}
+
+func (s *S3Resolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	bucket, ok := parseBucketName(query)
+	if !ok {
+		return results
+	}
+
+	for _, client := range s.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		result, err := findS3Bucket(ctx, client, bucket)
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func parseBucketName(query string) (string, bool) {
+	var bucket string
+
+	if name, ok := trimQueryPrefix(query, "s3://"); ok {
+		bucket = strings.TrimSuffix(name, "/")
+	} else if name, ok := trimQueryPrefix(query, "s3:"); ok {
+		bucket = name
+	} else if arn, ok := parseArn(query); ok && arn.service == "s3" {
+		bucket = arn.resource
+	} else if matches := bucketHostnamePattern.FindStringSubmatch(query); matches != nil {
+		bucket = matches[1]
+	} else {
+		return "", false
+	}
+
+	if isIPv4Address(bucket) {
+		return "", false
+	}
+
+	return bucket, bucketNamePattern.MatchString(bucket)
+}
+
+func findS3Bucket(ctx context.Context, client s3Client, bucket string) (*ResultSet, error) {
+	buckets, err := client.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	owned := false
+	for _, b := range buckets.Buckets {
+		if aws.StringValue(b.Name) == bucket {
+			owned = true
+			break
+		}
+	}
+
+	if !owned {
+		return nil, nil
+	}
+
+	location, err := client.GetBucketLocationWithContext(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	region := s3.NormalizeBucketLocation(aws.StringValue(location.LocationConstraint))
+	regionalClient := client.inRegion(region)
+
+	result := newResult("s3.bucket", client.account)
+	result.Metadata["bucket"] = []string{bucket}
+	result.Metadata["region"] = []string{region}
+	result.Links["s3_console"] = s3ConsoleLink(region, bucket)
+
+	versioning, err := regionalClient.GetBucketVersioningWithContext(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+	// Buckets that have never had versioning enabled have no status
+	result.Metadata["versioning"] = []string{"Disabled"}
+	if versioning.Status != nil {
+		result.Metadata["versioning"] = []string{*versioning.Status}
+	}
+
+	encryption, err := regionalClient.GetBucketEncryptionWithContext(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(bucket)})
+	switch {
+	case awsErrorCode(err) == "ServerSideEncryptionConfigurationNotFoundError":
+		result.Metadata["encryption"] = []string{"None"}
+	case err != nil:
+		bugsnag.Notify(err)
+		return nil, err
+	default:
+		rules := []string{}
+		for _, rule := range encryption.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault == nil {
+				continue
+			}
+			algorithm := aws.StringValue(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+			if keyID := aws.StringValue(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID); keyID != "" {
+				algorithm = fmt.Sprintf("%s (%s)", algorithm, keyID)
+			}
+			rules = append(rules, algorithm)
+		}
+		result.Metadata["encryption"] = rules
+	}
+
+	publicAccessBlock, err := regionalClient.GetPublicAccessBlockWithContext(ctx, &s3.GetPublicAccessBlockInput{Bucket: aws.String(bucket)})
+	switch {
+	case awsErrorCode(err) == "NoSuchPublicAccessBlockConfiguration":
+		result.Metadata["block_public_access"] = []string{"Not configured"}
+	case err != nil:
+		bugsnag.Notify(err)
+		return nil, err
+	default:
+		config := publicAccessBlock.PublicAccessBlockConfiguration
+		result.Metadata["block_public_access"] = []string{
+			"BlockPublicAcls=" + strconv.FormatBool(aws.BoolValue(config.BlockPublicAcls)),
+			"IgnorePublicAcls=" + strconv.FormatBool(aws.BoolValue(config.IgnorePublicAcls)),
+			"BlockPublicPolicy=" + strconv.FormatBool(aws.BoolValue(config.BlockPublicPolicy)),
+			"RestrictPublicBuckets=" + strconv.FormatBool(aws.BoolValue(config.RestrictPublicBuckets)),
+		}
+	}
+
+	policyStatus, err := regionalClient.GetBucketPolicyStatusWithContext(ctx, &s3.GetBucketPolicyStatusInput{Bucket: aws.String(bucket)})
+	switch {
+	case awsErrorCode(err) == "NoSuchBucketPolicy":
+		result.Metadata["policy_status"] = []string{"No bucket policy"}
+	case err != nil:
+		bugsnag.Notify(err)
+		return nil, err
+	default:
+		result.Metadata["policy_status"] = []string{"Not public"}
+		if aws.BoolValue(policyStatus.PolicyStatus.IsPublic) {
+			result.Metadata["policy_status"] = []string{"Public"}
+		}
+	}
+
+	tagging, err := regionalClient.GetBucketTaggingWithContext(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucket)})
+	switch {
+	case awsErrorCode(err) == "NoSuchTagSet":
+	case err != nil:
+		bugsnag.Notify(err)
+		return nil, err
+	default:
+		for _, tag := range tagging.TagSet {
+			result.Metadata[fmt.Sprintf("tag:%s", *tag.Key)] = []string{*tag.Value}
+		}
+	}
+
+	return &ResultSet{Kind: "s3.bucket", Results: []Result{result}}, nil
+}
+
+func s3ConsoleLink(region, bucket string) string {
+	return fmt.Sprintf("https://s3.console.aws.amazon.com/s3/buckets/%s/?region=%s", bucket, region)
+}
@@ -0,0 +1,31 @@
+package search
+
+import "testing"
+
+func TestParseBucketName(t *testing.T) {
+	cases := []struct {
+		query  string
+		bucket string
+		ok     bool
+	}{
+		{"assets-production", "", false},
+		{"web-prd-3", "", false},
+		{"api.internal.example.com", "", false},
+		{"s3://assets-production/", "assets-production", true},
+		{"s3:assets-production", "assets-production", true},
+		{"arn:aws:s3:::assets-production", "assets-production", true},
+		{"assets-production.s3.amazonaws.com", "assets-production", true},
+		{"assets-production.s3-eu-west-2.amazonaws.com", "assets-production", true},
+		{"assets-production.s3.eu-west-2.amazonaws.com", "assets-production", true},
+		{"s3://10.1.2.3", "", false},
+		{"s3:Assets_Production", "", false},
+		{"s3://assets-production/logs/today.gz", "", false},
+	}
+
+	for _, c := range cases {
+		bucket, ok := parseBucketName(c.query)
+		if ok != c.ok || (ok && bucket != c.bucket) {
+			t.Errorf("expected %q to parse as (%q, %v), got (%q, %v)", c.query, c.bucket, c.ok, bucket, ok)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Resolver finds resources that match a query typed in to slack. Queries
+// that a resolver doesn't recognise should return no results without
+// making any calls to AWS.
+type Resolver interface {
+	Search(ctx context.Context, query string) []ResultSet
+}
+
+// Annotator adds extra details to results found by other resolvers, such
+// as the CloudFormation stack a resource belongs to. Resolvers that also
+// implement Annotator are given every result set once the search is done.
+type Annotator interface {
+	Annotate(ctx context.Context, sets []ResultSet)
+}
+
+// Reporter is a resolver that answers a keyword like `hygiene` with a
+// report, rather than looking resources up. Queries that start with a
+// reporter's keyword are only given to that reporter, so that asking for a
+// report doesn't also search every account for it.
+type Reporter interface {
+	ReportKeyword() string
+}
+
+// Searcher runs a query against several resolvers at once
+type Searcher struct {
+	accounts  []Account
+	resolvers []Resolver
+	cache     *resultCache
+}
+
+func NewSearcher(accounts []Account, resolvers ...Resolver) *Searcher {
+	return &Searcher{accounts: accounts, resolvers: resolvers}
+}
+
+// CacheResults makes the searcher reuse the results of a query for ttl,
+// unless the query includes FreshFlag. Identical searches that run at the
+// same time also share their results.
+func (s *Searcher) CacheResults(ttl time.Duration) {
+	s.cache = newResultCache(ttl)
+}
+
+// Search asks every resolver for results in parallel. Result sets are
+// returned in the order the resolvers were given to NewSearcher, so that
+// the response to the same query is always laid out the same way.
+func (s *Searcher) Search(ctx context.Context, query string) []ResultSet {
+	query, fresh := trimFreshFlag(query)
+	if s.cache == nil {
+		return s.search(ctx, query)
+	}
+
+	return s.cache.get(ctx, cacheKey(ctx, query), fresh, func(ctx context.Context) []ResultSet {
+		return s.search(ctx, query)
+	})
+}
+
+func (s *Searcher) search(ctx context.Context, query string) []ResultSet {
+	query = strings.TrimSpace(query)
+
+	if reporter := s.reporter(query); reporter != nil {
+		results := reporter.Search(ctx, query)
+		s.annotate(ctx, results)
+
+		return results
+	}
+
+	// Pasted log lines or alerts are searched for every identifier in them
+	if strings.ContainsAny(query, " \t\n") {
+		if identifiers := extractIdentifiers(query); len(identifiers) > 1 {
+			return s.bulkSearch(ctx, identifiers)
+		}
+	}
+
+	// ARNs say exactly which account and region a resource is in, and which
+	// resolver understands it
+	if arn, ok := parseArn(query); ok {
+		if !s.canSee(arn.accountID, arn.region) {
+			return []ResultSet{unknownAccountResultSet(arn, s.accounts)}
+		}
+
+		ctx = scopeToAccount(ctx, arn.accountID, arn.region)
+		query = arn.query()
+	}
+
+	// EC2 private DNS names contain the instance's IP or ID, and its region
+	if target, region, ok := parseEc2PrivateHostname(query); ok {
+		ctx = scopeToAccount(ctx, "", region)
+		query = target
+	}
+
+	resultsByResolver := make([][]ResultSet, len(s.resolvers))
+
+	var wg sync.WaitGroup
+	for i, resolver := range s.resolvers {
+		wg.Add(1)
+		go func(i int, resolver Resolver) {
+			defer wg.Done()
+			resultsByResolver[i] = resolver.Search(ctx, query)
+		}(i, resolver)
+	}
+	wg.Wait()
+
+	results := []ResultSet{}
+	for _, sets := range resultsByResolver {
+		results = append(results, sets...)
+	}
+	s.annotate(ctx, results)
+
+	return results
+}
+
+// reporter returns the resolver whose report keyword the query starts
+// with, if there is one
+func (s *Searcher) reporter(query string) Resolver {
+	fields := strings.Fields(strings.ToLower(query))
+	if len(fields) == 0 {
+		return nil
+	}
+
+	for _, resolver := range s.resolvers {
+		if reporter, ok := resolver.(Reporter); ok && reporter.ReportKeyword() == fields[0] {
+			return resolver
+		}
+	}
+
+	return nil
+}
+
+// annotate gives every resolver that's an Annotator the results
+func (s *Searcher) annotate(ctx context.Context, results []ResultSet) {
+	for _, resolver := range s.resolvers {
+		if annotator, ok := resolver.(Annotator); ok {
+			annotator.Annotate(ctx, results)
+		}
+	}
+}
+
+// canSee is true if slash-infra has a role for the account in the region.
+// ARNs for global resources, like S3 buckets, have no account or region,
+// which matches any account.
+func (s *Searcher) canSee(accountID, region string) bool {
+	for _, account := range s.accounts {
+		if (accountID == "" || account.ID == accountID) && (region == "" || account.Region == region) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trimQueryPrefix removes a prefix like `asg:` that users can add to a
+// query to say which kind of resource they're looking for
+func trimQueryPrefix(query, prefix string) (string, bool) {
+	if !strings.HasPrefix(query, prefix) {
+		return query, false
+	}
+
+	return strings.TrimSpace(query[len(prefix):]), true
+}
+
+// awsErrorCode returns the code of errors returned by the AWS SDK, which
+// lets us tell "this resource doesn't exist" apart from real failures
+func awsErrorCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+
+	return ""
+}
+
+// isResourceName is true for queries that could be the name of a
+// resource, rather than an ID, IP address or hostname
+func isResourceName(query string) bool {
+	if query == "" || isEc2InstanceID(query) {
+		return false
+	}
+
+	for _, c := range query {
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,224 @@
+package search
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSnapshotRetention is how long inventory snapshots are kept on disk
+// when no retention is configured
+const DefaultSnapshotRetention = 7 * 24 * time.Hour
+
+// snapshotFileTimeFormat names snapshot files so that they sort by the
+// time they were taken
+const snapshotFileTimeFormat = "20060102T150405Z"
+
+const snapshotFileExtension = ".json.gz"
+
+// SnapshotStore keeps inventory snapshots on local disk, so that we can
+// answer questions about instances that have since been terminated. Each
+// account's snapshots are kept in their own directory:
+//
+//	{dir}/{account alias}/20060102T150405Z.json.gz
+type SnapshotStore struct {
+	dir       string
+	retention time.Duration
+
+	mu sync.Mutex
+}
+
+func NewSnapshotStore(dir string, retention time.Duration) *SnapshotStore {
+	return &SnapshotStore{dir: dir, retention: retention}
+}
+
+// Save writes a snapshot to disk
+func (s *SnapshotStore) Save(snapshot *InventorySnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, snapshot.Alias)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that a crash part way through
+	// doesn't leave a corrupt snapshot behind
+	tmp, err := ioutil.TempFile(dir, ".snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	compressed := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(compressed).Encode(snapshot); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := compressed.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(snapshot.Alias, snapshot.TakenAt))
+}
+
+// Prune deletes snapshots that are older than the retention period
+func (s *SnapshotStore) Prune(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	aliases, err := s.aliases()
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-s.retention)
+	for _, alias := range aliases {
+		times, err := s.times(alias)
+		if err != nil {
+			return err
+		}
+
+		for _, takenAt := range times {
+			if !takenAt.Before(cutoff) {
+				break
+			}
+
+			if err := os.Remove(s.path(alias, takenAt)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Aliases lists the accounts that have snapshots
+func (s *SnapshotStore) Aliases() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.aliases()
+}
+
+// At returns the account's latest snapshot that was taken at or before t,
+// or nil if there isn't one
+func (s *SnapshotStore) At(alias string, t time.Time) (*InventorySnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	times, err := s.times(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	i := sort.Search(len(times), func(i int) bool { return times[i].After(t) })
+	if i == 0 {
+		return nil, nil
+	}
+
+	return s.load(alias, times[i-1])
+}
+
+// Oldest returns the account's first snapshot, or nil if there isn't one
+func (s *SnapshotStore) Oldest(alias string) (*InventorySnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	times, err := s.times(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(times) == 0 {
+		return nil, nil
+	}
+
+	return s.load(alias, times[0])
+}
+
+func (s *SnapshotStore) aliases() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			aliases = append(aliases, entry.Name())
+		}
+	}
+
+	return aliases, nil
+}
+
+// times lists when each of the account's snapshots were taken, oldest
+// first
+func (s *SnapshotStore) times(alias string) ([]time.Time, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(s.dir, alias))
+	if os.IsNotExist(err) {
+		return []time.Time{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	times := []time.Time{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), snapshotFileExtension) {
+			continue
+		}
+
+		takenAt, err := time.Parse(snapshotFileTimeFormat, strings.TrimSuffix(entry.Name(), snapshotFileExtension))
+		if err != nil {
+			continue
+		}
+
+		times = append(times, takenAt)
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	return times, nil
+}
+
+func (s *SnapshotStore) load(alias string, takenAt time.Time) (*InventorySnapshot, error) {
+	file, err := os.Open(s.path(alias, takenAt))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read snapshot for %s at %s: %s", alias, takenAt, err)
+	}
+
+	snapshot := &InventorySnapshot{}
+	if err := json.NewDecoder(compressed).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("could not read snapshot for %s at %s: %s", alias, takenAt, err)
+	}
+
+	snapshot.index()
+
+	return snapshot, nil
+}
+
+func (s *SnapshotStore) path(alias string, takenAt time.Time) string {
+	return filepath.Join(s.dir, alias, takenAt.UTC().Format(snapshotFileTimeFormat)+snapshotFileExtension)
+}
@@ -0,0 +1,147 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+// SNSSubscriptionsToShow is how many of a topic's subscriptions are
+// included in its result
+const SNSSubscriptionsToShow = 10
+
+type snsSDK interface {
+	GetTopicAttributesWithContext(ctx aws.Context, input *sns.GetTopicAttributesInput, opts ...request.Option) (*sns.GetTopicAttributesOutput, error)
+	ListSubscriptionsByTopicPagesWithContext(ctx aws.Context, input *sns.ListSubscriptionsByTopicInput, fn func(*sns.ListSubscriptionsByTopicOutput, bool) bool, opts ...request.Option) error
+}
+
+type snsClient struct {
+	snsSDK
+	account Account
+}
+
+func buildSnsClients(accounts []Account) []snsClient {
+	clients := []snsClient{}
+
+	for _, account := range accounts {
+		svc := sns.New(account.session, account.config)
+
+		clients = append(clients, snsClient{snsSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+func NewSns(accounts []Account) *SNSResolver {
+	return &SNSResolver{clients: buildSnsClients(accounts)}
+}
+
+// SNSResolver finds topics by name, e.g. `sns:payments-alerts`, or by the
+// ARN of the topic or one of its subscriptions
+type SNSResolver struct {
+	clients []snsClient
+}
+
+func (s *SNSResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	name, ok := trimQueryPrefix(query, "sns:")
+	arn, isArn := parseArn(query)
+	if (ok && name == "") || (!ok && !(isArn && arn.service == "sns")) {
+		return results
+	}
+
+	for _, client := range s.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		topicArn := fmt.Sprintf("arn:aws:sns:%s:%s:%s", client.account.Region, client.account.ID, name)
+		if !ok {
+			// Subscription ARNs are the topic's ARN followed by an ID
+			topicArn = strings.TrimSuffix(arn.raw, arn.resource) + strings.SplitN(arn.resource, ":", 2)[0]
+		}
+
+		result, err := findTopic(ctx, client, topicArn)
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func findTopic(ctx context.Context, client snsClient, topicArn string) (*ResultSet, error) {
+	attributes, err := client.GetTopicAttributesWithContext(
+		ctx,
+		&sns.GetTopicAttributesInput{TopicArn: aws.String(topicArn)},
+	)
+	if err != nil {
+		if awsErrorCode(err) == sns.ErrCodeNotFoundException {
+			return nil, nil
+		}
+
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	subscriptions := []string{}
+	err = client.ListSubscriptionsByTopicPagesWithContext(
+		ctx,
+		&sns.ListSubscriptionsByTopicInput{TopicArn: aws.String(topicArn)},
+		func(page *sns.ListSubscriptionsByTopicOutput, lastPage bool) bool {
+			for _, subscription := range page.Subscriptions {
+				subscriptions = append(subscriptions, fmt.Sprintf(
+					"%s: %s",
+					aws.StringValue(subscription.Protocol),
+					aws.StringValue(subscription.Endpoint),
+				))
+			}
+			return len(subscriptions) < SNSSubscriptionsToShow
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	if len(subscriptions) > SNSSubscriptionsToShow {
+		subscriptions = subscriptions[:SNSSubscriptionsToShow]
+	}
+
+	result := topicToResult(client.account, topicArn, aws.StringValueMap(attributes.Attributes))
+	result.Metadata["subscriptions"] = subscriptions
+
+	return &ResultSet{Kind: "sns.topic", Results: []Result{result}}, nil
+}
+
+func topicToResult(account Account, topicArn string, attributes map[string]string) Result {
+	result := newResult("sns.topic", account)
+	result.Metadata["topic_arn"] = []string{topicArn}
+	result.Metadata["topic_name"] = []string{topicArn[strings.LastIndex(topicArn, ":")+1:]}
+	result.Metadata["display_name"] = []string{attributes["DisplayName"]}
+	result.Metadata["subscriptions_confirmed"] = []string{attributes["SubscriptionsConfirmed"]}
+	result.Metadata["subscriptions_pending"] = []string{attributes["SubscriptionsPending"]}
+
+	if keyID, ok := attributes["KmsMasterKeyId"]; ok {
+		result.Metadata["kms_key_id"] = []string{keyID}
+	}
+
+	result.Links["sns_console"] = snsConsoleLink(account.Region, topicArn)
+
+	return result
+}
+
+func snsConsoleLink(region, topicArn string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/sns/v3/home?region=%s#/topic/%s", region, topicArn)
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	bugsnag "github.com/bugsnag/bugsnag-go"
+)
+
+type sqsSDK interface {
+	GetQueueUrlWithContext(ctx aws.Context, input *sqs.GetQueueUrlInput, opts ...request.Option) (*sqs.GetQueueUrlOutput, error)
+	GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error)
+	ListQueueTagsWithContext(ctx aws.Context, input *sqs.ListQueueTagsInput, opts ...request.Option) (*sqs.ListQueueTagsOutput, error)
+}
+
+type sqsClient struct {
+	sqsSDK
+	account Account
+}
+
+func buildSqsClients(accounts []Account) []sqsClient {
+	clients := []sqsClient{}
+
+	for _, account := range accounts {
+		svc := sqs.New(account.session, account.config)
+
+		clients = append(clients, sqsClient{sqsSDK: svc, account: account})
+	}
+
+	return clients
+}
+
+func NewSqs(accounts []Account) *SQSResolver {
+	return &SQSResolver{clients: buildSqsClients(accounts)}
+}
+
+// SQSResolver finds queues by URL, ARN or name, e.g. `sqs:payments-events`
+type SQSResolver struct {
+	clients []sqsClient
+}
+
+// sqsRedrivePolicy is the JSON encoded RedrivePolicy queue attribute
+type sqsRedrivePolicy struct {
+	DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+	MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+}
+
+func (s *SQSResolver) Search(ctx context.Context, query string) []ResultSet {
+	results := []ResultSet{}
+
+	accountID, region, name, ok := parseQueueReference(query)
+	if !ok {
+		return results
+	}
+
+	// URLs and ARNs tell us exactly which account and region to look in
+	if accountID != "" {
+		ctx = scopeToAccount(ctx, accountID, region)
+	}
+
+	for _, client := range s.clients {
+		if !inScope(ctx, client.account) {
+			continue
+		}
+
+		result, err := findQueue(ctx, client, name, accountID)
+		if err != nil {
+			log.Print(err)
+		}
+
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
+
+func findQueue(ctx context.Context, client sqsClient, name, accountID string) (*ResultSet, error) {
+	input := &sqs.GetQueueUrlInput{QueueName: aws.String(name)}
+	if accountID != "" {
+		input.QueueOwnerAWSAccountId = aws.String(accountID)
+	}
+
+	queue, err := client.GetQueueUrlWithContext(ctx, input)
+	if err != nil {
+		if awsErrorCode(err) == sqs.ErrCodeQueueDoesNotExist {
+			return nil, nil
+		}
+
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	attributes, err := client.GetQueueAttributesWithContext(
+		ctx,
+		&sqs.GetQueueAttributesInput{
+			QueueUrl:       queue.QueueUrl,
+			AttributeNames: []*string{aws.String(sqs.QueueAttributeNameAll)},
+		},
+	)
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	tags, err := client.ListQueueTagsWithContext(ctx, &sqs.ListQueueTagsInput{QueueUrl: queue.QueueUrl})
+	if err != nil {
+		bugsnag.Notify(err)
+		return nil, err
+	}
+
+	result := queueToResult(client.account, name, aws.StringValue(queue.QueueUrl), aws.StringValueMap(attributes.Attributes))
+	for key, value := range tags.Tags {
+		result.Metadata[fmt.Sprintf("tag:%s", key)] = []string{aws.StringValue(value)}
+	}
+
+	return &ResultSet{Kind: "sqs.queue", Results: []Result{result}}, nil
+}
+
+func queueToResult(account Account, name, queueURL string, attributes map[string]string) Result {
+	result := newResult("sqs.queue", account)
+	result.Metadata["queue_name"] = []string{name}
+	result.Metadata["queue_url"] = []string{queueURL}
+	result.Metadata["queue_arn"] = []string{attributes["QueueArn"]}
+	result.Metadata["messages_available"] = []string{attributes["ApproximateNumberOfMessages"]}
+	result.Metadata["messages_in_flight"] = []string{attributes["ApproximateNumberOfMessagesNotVisible"]}
+	result.Metadata["messages_delayed"] = []string{attributes["ApproximateNumberOfMessagesDelayed"]}
+	result.Metadata["retention"] = []string{formatSeconds(attributes["MessageRetentionPeriod"])}
+	result.Metadata["visibility_timeout"] = []string{formatSeconds(attributes["VisibilityTimeout"])}
+	result.Metadata["fifo"] = []string{strconv.FormatBool(attributes["FifoQueue"] == "true")}
+
+	if policy, ok := attributes["RedrivePolicy"]; ok {
+		var redrive sqsRedrivePolicy
+		if err := json.Unmarshal([]byte(policy), &redrive); err != nil {
+			bugsnag.Notify(err)
+			log.Print(err)
+		} else {
+			result.Metadata["dead_letter_queue"] = []string{redrive.DeadLetterTargetArn}
+			result.Metadata["max_receive_count"] = []string{redrive.MaxReceiveCount.String()}
+		}
+	}
+
+	result.Links["sqs_console"] = sqsConsoleLink(account.Region, queueURL)
+
+	return result
+}
+
+// parseQueueReference understands queue URLs, ARNs and `sqs:` prefixed
+// names. The account ID and region are empty for names, as they could be
+// in any account.
+func parseQueueReference(query string) (accountID, region, name string, ok bool) {
+	if name, ok := trimQueryPrefix(query, "sqs:"); ok {
+		return "", "", name, name != ""
+	}
+
+	if arn, ok := parseArn(query); ok {
+		return arn.accountID, arn.region, arn.resource, arn.service == "sqs"
+	}
+
+	if !strings.HasPrefix(query, "https://") {
+		return "", "", "", false
+	}
+
+	queueURL, err := url.Parse(query)
+	if err != nil {
+		return "", "", "", false
+	}
+
+	// e.g. sqs.eu-west-2.amazonaws.com, eu-west-2.queue.amazonaws.com or
+	// the legacy queue.amazonaws.com for us-east-1
+	host := strings.TrimSuffix(queueURL.Hostname(), ".amazonaws.com")
+	switch {
+	case host == "queue":
+		region = "us-east-1"
+	case strings.HasPrefix(host, "sqs."):
+		region = strings.TrimPrefix(host, "sqs.")
+	case strings.HasSuffix(host, ".queue"):
+		region = strings.TrimSuffix(host, ".queue")
+	default:
+		return "", "", "", false
+	}
+
+	path := strings.Split(strings.Trim(queueURL.Path, "/"), "/")
+	if len(path) != 2 {
+		return "", "", "", false
+	}
+
+	return path[0], region, path[1], true
+}
+
+// formatSeconds turns a number of seconds from a queue attribute into
+// something readable, e.g. "4 days" or "30s"
+func formatSeconds(seconds string) string {
+	n, err := strconv.Atoi(seconds)
+	if err != nil {
+		return seconds
+	}
+
+	if n >= 86400 && n%86400 == 0 {
+		return fmt.Sprintf("%d days", n/86400)
+	}
+
+	return (time.Duration(n) * time.Second).String()
+}
+
+func sqsConsoleLink(region, queueURL string) string {
+	return fmt.Sprintf("https://console.aws.amazon.com/sqs/v2/home?region=%s#/queues/%s", region, url.QueryEscape(queueURL))
+}
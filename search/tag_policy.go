@@ -0,0 +1,226 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TagRule is one tag that resources should have. The tag can be limited to
+// a list of values, or to values that match a regular expression.
+type TagRule struct {
+	Key     string   `json:"key"`
+	Values  []string `json:"values,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	// Optional tags don't have to be set, but must be valid if they are
+	Optional bool `json:"optional,omitempty"`
+	// Kinds are the kinds of result the rule applies to, e.g. `ebs.volume`.
+	// Rules apply to EC2 instances if no kinds are given.
+	Kinds []string `json:"kinds,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// TagPolicy is the set of tags resources are expected to have. Results
+// that break the policy have `policy_violations` metadata.
+type TagPolicy struct {
+	Rules []TagRule `json:"rules"`
+}
+
+// DefaultTagPolicy requires the tags that FormatEc2InstanceAsAttachment
+// shows
+func DefaultTagPolicy() *TagPolicy {
+	policy := &TagPolicy{Rules: []TagRule{}}
+	for _, key := range RequiredInstanceTags {
+		policy.Rules = append(policy.Rules, TagRule{Key: key})
+	}
+
+	return policy
+}
+
+// LoadTagPolicy reads a policy from a JSON file, e.g.
+//
+//	{
+//	  "rules": [
+//	    {"key": "Environment", "values": ["production", "staging"]},
+//	    {"key": "Owner", "pattern": "^team-[a-z]+$", "kinds": ["ec2.instance", "ebs.volume"]}
+//	  ]
+//	}
+func LoadTagPolicy(path string) (*TagPolicy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &TagPolicy{}
+	if err := json.Unmarshal(b, policy); err != nil {
+		return nil, fmt.Errorf("could not read tag policy from %s: %s", path, err)
+	}
+
+	for i, rule := range policy.Rules {
+		if rule.Key == "" {
+			return nil, fmt.Errorf("rule %d in tag policy %s has no key", i+1, path)
+		}
+
+		if rule.Pattern != "" {
+			if policy.Rules[i].pattern, err = regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("could not parse pattern for %s in tag policy %s: %s", rule.Key, path, err)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// appliesTo is true if the rule should be checked for a kind of result
+func (r TagRule) appliesTo(kind string) bool {
+	if len(r.Kinds) == 0 {
+		return kind == "ec2.instance"
+	}
+
+	return containsString(r.Kinds, kind)
+}
+
+// check describes how result breaks the rule, or returns "" if it doesn't
+func (r TagRule) check(result Result) string {
+	value, ok := result.Metadata["tag:"+r.Key]
+	switch {
+	case !ok && r.Optional:
+		return ""
+	case !ok:
+		return fmt.Sprintf("missing `%s` tag", r.Key)
+	case len(r.Values) > 0 && !containsString(r.Values, strings.Join(value, ",")):
+		return fmt.Sprintf("`%s` is `%s`, should be one of %s", r.Key, strings.Join(value, ","), strings.Join(r.Values, ", "))
+	case r.pattern != nil && !r.pattern.MatchString(strings.Join(value, ",")):
+		return fmt.Sprintf("`%s` is `%s`, should match `%s`", r.Key, strings.Join(value, ","), r.Pattern)
+	}
+
+	return ""
+}
+
+// violations lists every way result breaks the policy
+func (p *TagPolicy) violations(result Result) []string {
+	violations := []string{}
+
+	for _, rule := range p.Rules {
+		if !rule.appliesTo(result.Kind) {
+			continue
+		}
+
+		if violation := rule.check(result); violation != "" {
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations
+}
+
+func NewCompliance(policy *TagPolicy, inventory *Inventory) *ComplianceResolver {
+	return &ComplianceResolver{policy: policy, inventory: inventory}
+}
+
+// ComplianceResolver checks results against the tag policy, and summarises
+// how well each account's instances follow it, e.g. `compliance`
+type ComplianceResolver struct {
+	policy    *TagPolicy
+	inventory *Inventory
+}
+
+func (c *ComplianceResolver) ReportKeyword() string {
+	return "compliance"
+}
+
+func (c *ComplianceResolver) Search(ctx context.Context, query string) []ResultSet {
+	if strings.ToLower(query) != "compliance" {
+		return []ResultSet{}
+	}
+
+	snapshots := []*InventorySnapshot{}
+	for _, snapshot := range c.inventory.allSnapshots() {
+		if inScope(ctx, Account{ID: snapshot.AccountID, Region: snapshot.Region}) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Alias < snapshots[j].Alias
+	})
+
+	results := []Result{}
+	for _, snapshot := range snapshots {
+		results = append(results, c.summarise(snapshot))
+	}
+
+	return []ResultSet{
+		{Kind: "compliance.summary", Results: results},
+	}
+}
+
+// summarise counts an account's instances that follow the policy, and
+// how often each kind of violation happens
+func (c *ComplianceResolver) summarise(snapshot *InventorySnapshot) Result {
+	instances, compliant := 0, 0
+	counts := map[string]int{}
+
+	for _, instance := range snapshot.Resources {
+		if isTerminated(instance) {
+			continue
+		}
+		instances++
+
+		// Count by tag rather than by message, as the values in messages vary
+		broken := map[string]bool{}
+		for _, rule := range c.policy.Rules {
+			if rule.appliesTo(instance.Kind) && rule.check(instance) != "" {
+				broken[rule.Key] = true
+			}
+		}
+
+		if len(broken) == 0 {
+			compliant++
+		}
+		for key := range broken {
+			counts[key]++
+		}
+	}
+
+	summary := []string{}
+	seen := map[string]bool{}
+	for _, rule := range c.policy.Rules {
+		if count := counts[rule.Key]; count > 0 && !seen[rule.Key] {
+			seen[rule.Key] = true
+			summary = append(summary, fmt.Sprintf("%s: %d", rule.Key, count))
+		}
+	}
+
+	result := snapshot.withFreshness(Result{
+		Kind: "compliance.account",
+		Metadata: map[string][]string{
+			"account":    []string{snapshot.Alias},
+			"account_id": []string{snapshot.AccountID},
+			"region":     []string{snapshot.Region},
+		},
+		Links: map[string]string{},
+	})
+	result.Metadata["instances"] = []string{strconv.Itoa(instances)}
+	result.Metadata["compliant"] = []string{strconv.Itoa(compliant)}
+	result.Metadata["violations_by_tag"] = summary
+
+	return result
+}
+
+// Annotate adds `policy_violations` metadata to every result that breaks
+// the tag policy
+func (c *ComplianceResolver) Annotate(ctx context.Context, sets []ResultSet) {
+	for _, set := range sets {
+		for _, result := range set.Results {
+			if violations := c.policy.violations(result); len(violations) > 0 {
+				result.Metadata["policy_violations"] = violations
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTagRuleCheck(t *testing.T) {
+	instance := func(tags map[string]string) Result {
+		result := Result{Kind: "ec2.instance", Metadata: map[string][]string{}}
+		for key, value := range tags {
+			result.Metadata["tag:"+key] = []string{value}
+		}
+		return result
+	}
+
+	cases := []struct {
+		name     string
+		rule     TagRule
+		instance Result
+		want     string
+	}{
+		{
+			name:     "Required tag is set",
+			rule:     TagRule{Key: "Role"},
+			instance: instance(map[string]string{"Role": "web"}),
+			want:     "",
+		},
+		{
+			name:     "Required tag is missing",
+			rule:     TagRule{Key: "Role"},
+			instance: instance(nil),
+			want:     "missing `Role` tag",
+		},
+		{
+			name:     "Optional tag is missing",
+			rule:     TagRule{Key: "Owner", Optional: true},
+			instance: instance(nil),
+			want:     "",
+		},
+		{
+			name:     "Optional tag is invalid",
+			rule:     TagRule{Key: "Environment", Values: []string{"production", "staging"}, Optional: true},
+			instance: instance(map[string]string{"Environment": "prod"}),
+			want:     "`Environment` is `prod`, should be one of production, staging",
+		},
+		{
+			name:     "Value is allowed",
+			rule:     TagRule{Key: "Environment", Values: []string{"production", "staging"}},
+			instance: instance(map[string]string{"Environment": "staging"}),
+			want:     "",
+		},
+		{
+			name:     "Value matches the pattern",
+			rule:     TagRule{Key: "Owner", Pattern: "^team-[a-z]+$", pattern: regexp.MustCompile("^team-[a-z]+$")},
+			instance: instance(map[string]string{"Owner": "team-payments"}),
+			want:     "",
+		},
+		{
+			name:     "Value doesn't match the pattern",
+			rule:     TagRule{Key: "Owner", Pattern: "^team-[a-z]+$", pattern: regexp.MustCompile("^team-[a-z]+$")},
+			instance: instance(map[string]string{"Owner": "bob"}),
+			want:     "`Owner` is `bob`, should match `^team-[a-z]+$`",
+		},
+		{
+			name:     "Empty values count as set",
+			rule:     TagRule{Key: "Role"},
+			instance: instance(map[string]string{"Role": ""}),
+			want:     "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.rule.check(c.instance); got != c.want {
+				t.Errorf("expected %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestTagRuleAppliesTo(t *testing.T) {
+	if !(TagRule{Key: "Role"}).appliesTo("ec2.instance") {
+		t.Error("expected rules without kinds to apply to instances")
+	}
+	if (TagRule{Key: "Role"}).appliesTo("ebs.volume") {
+		t.Error("expected rules without kinds not to apply to volumes")
+	}
+	if !(TagRule{Key: "Owner", Kinds: []string{"ebs.volume"}}).appliesTo("ebs.volume") {
+		t.Error("expected rules to apply to the kinds they list")
+	}
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ResultTemplate replaces the fields that are shown for a kind of result,
+// so that teams can show the tags they use without changing the code
+type ResultTemplate struct {
+	Fields []TemplateField `json:"fields"`
+	Links  []TemplateLink  `json:"links,omitempty"`
+}
+
+// TemplateField shows a metadata key, e.g. `tag:team` or `private_ips`
+type TemplateField struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	// Wide fields take up a whole line rather than half of one
+	Wide bool `json:"wide,omitempty"`
+}
+
+// TemplateLink shows one of the result's links, e.g. `config_timeline`
+type TemplateLink struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// LoadResultTemplates reads templates from a JSON file, keyed by the kind
+// of result they apply to, e.g.
+//
+//	{
+//	  "ec2.instance": {
+//	    "fields": [
+//	      {"key": "tag:team", "label": "Team"},
+//	      {"key": "tag:service", "label": "Service"},
+//	      {"key": "private_ips", "label": "Private IP(s)"}
+//	    ],
+//	    "links": [
+//	      {"key": "config_timeline", "label": "AWS config timeline"}
+//	    ]
+//	  }
+//	}
+func LoadResultTemplates(path string) (map[string]ResultTemplate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := map[string]ResultTemplate{}
+	if err := json.Unmarshal(b, &templates); err != nil {
+		return nil, fmt.Errorf("could not read result templates from %s: %s", path, err)
+	}
+
+	for kind, template := range templates {
+		for i, field := range template.Fields {
+			if field.Key == "" {
+				return nil, fmt.Errorf("field %d of the %s template in %s has no key", i+1, kind, path)
+			}
+		}
+
+		for i, link := range template.Links {
+			if link.Key == "" {
+				return nil, fmt.Errorf("link %d of the %s template in %s has no key", i+1, kind, path)
+			}
+		}
+	}
+
+	return templates, nil
+}
@@ -0,0 +1,256 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of event that are posted to channels watching an instance
+const (
+	WatchStateChanged      = "state_changed"
+	WatchTerminated        = "terminated"
+	WatchStatusCheckFailed = "status_check_failed"
+)
+
+// Subscription is a channel watching an instance ID, e.g. `i-0123...`, or
+// every instance with a tag, e.g. `tag:Role=db`
+type Subscription struct {
+	ChannelID string
+	Target    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// ParseWatchTarget checks that target is something that can be watched,
+// and tidies it up
+func ParseWatchTarget(target string) (string, bool) {
+	target = strings.TrimSpace(target)
+
+	if isEc2InstanceID(target) {
+		return target, true
+	}
+
+	if tag, ok := trimQueryPrefix(target, "tag:"); ok {
+		parts := strings.SplitN(tag, "=", 2)
+		if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			return target, true
+		}
+	}
+
+	return "", false
+}
+
+// matches is true if the subscription is watching instance
+func (s Subscription) matches(instance Result) bool {
+	if isEc2InstanceID(s.Target) {
+		return instance.GetMetadata("instance_id") == s.Target
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(s.Target, "tag:"), "=", 2)
+
+	return len(parts) == 2 && instance.GetMetadata("tag:"+parts[0]) == parts[1]
+}
+
+// SubscriptionStore keeps every channel's subscriptions in a JSON file, so
+// that they survive restarts
+type SubscriptionStore struct {
+	path string
+
+	mu            sync.Mutex
+	subscriptions []Subscription
+}
+
+// NewSubscriptionStore loads the subscriptions saved at path. It's fine
+// for the file not to exist yet.
+func NewSubscriptionStore(path string) (*SubscriptionStore, error) {
+	store := &SubscriptionStore{path: path, subscriptions: []Subscription{}}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &store.subscriptions); err != nil {
+		return nil, fmt.Errorf("could not read subscriptions from %s: %s", path, err)
+	}
+
+	return store, nil
+}
+
+// Add subscribes a channel to a target. It's false if the channel was
+// already watching it.
+func (s *SubscriptionStore) Add(subscription Subscription) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.subscriptions {
+		if existing.ChannelID == subscription.ChannelID && existing.Target == subscription.Target {
+			return false, nil
+		}
+	}
+
+	s.subscriptions = append(s.subscriptions, subscription)
+
+	return true, s.save()
+}
+
+// Remove unsubscribes a channel from a target. It's false if the channel
+// wasn't watching it.
+func (s *SubscriptionStore) Remove(channelID, target string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := []Subscription{}
+	for _, existing := range s.subscriptions {
+		if existing.ChannelID != channelID || existing.Target != target {
+			kept = append(kept, existing)
+		}
+	}
+
+	if len(kept) == len(s.subscriptions) {
+		return false, nil
+	}
+
+	s.subscriptions = kept
+
+	return true, s.save()
+}
+
+// List returns a channel's subscriptions
+func (s *SubscriptionStore) List(channelID string) []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriptions := []Subscription{}
+	for _, subscription := range s.subscriptions {
+		if subscription.ChannelID == channelID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	return subscriptions
+}
+
+func (s *SubscriptionStore) all() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Subscription{}, s.subscriptions...)
+}
+
+// save writes every subscription to disk. The caller must hold s.mu.
+func (s *SubscriptionStore) save() error {
+	b, err := json.MarshalIndent(s.subscriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that a crash part way through
+	// doesn't lose every subscription
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".subscriptions")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Watcher compares each new inventory snapshot with the last one, and
+// tells subscribed channels when an instance they're watching changes
+// state, is terminated, or fails a status check
+type Watcher struct {
+	subscriptions *SubscriptionStore
+	notify        func(Subscription, Result)
+}
+
+// NewWatcher creates a watcher that calls notify for every event. Events
+// are results of kind "watch.event", with `event` and `details` metadata.
+func NewWatcher(subscriptions *SubscriptionStore, notify func(Subscription, Result)) *Watcher {
+	return &Watcher{subscriptions: subscriptions, notify: notify}
+}
+
+// Compare is given to Inventory.OnRefresh
+func (w *Watcher) Compare(before, after *InventorySnapshot) {
+	// Nothing can have changed the first time an account is crawled
+	if before == nil {
+		return
+	}
+
+	subscriptions := w.subscriptions.all()
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	for _, event := range watchEvents(before, after) {
+		for _, subscription := range subscriptions {
+			if subscription.matches(event) {
+				w.notify(subscription, event)
+			}
+		}
+	}
+}
+
+// watchEvents lists what happened to instances between two snapshots of
+// the same account
+func watchEvents(before, after *InventorySnapshot) []Result {
+	events := []Result{}
+
+	event := func(instance Result, kind string, details string) {
+		result := after.withFreshness(instance)
+		result.Kind = "watch.event"
+		result.Metadata["event"] = []string{kind}
+		result.Metadata["details"] = []string{details}
+
+		events = append(events, result)
+	}
+
+	for _, instance := range after.Resources {
+		previous, existed := before.byID[instance.GetMetadata("instance_id")]
+		if !existed {
+			continue
+		}
+
+		from, to := previous.GetMetadata("instance_state"), instance.GetMetadata("instance_state")
+		switch {
+		case from == to:
+		case to == "terminated":
+			event(instance, WatchTerminated, fmt.Sprintf("%s → %s", from, to))
+		default:
+			event(instance, WatchStateChanged, fmt.Sprintf("%s → %s", from, to))
+		}
+
+		for _, check := range []string{"system_status", "instance_status"} {
+			if instance.GetMetadata(check) == "impaired" && previous.GetMetadata(check) != "impaired" {
+				event(instance, WatchStatusCheckFailed, fmt.Sprintf("%s is impaired", strings.Replace(check, "_", " ", -1)))
+			}
+		}
+	}
+
+	// Instances disappear from DescribeInstances about an hour after
+	// they're terminated, so we may never see the terminated state
+	for _, instance := range before.Resources {
+		if _, exists := after.byID[instance.GetMetadata("instance_id")]; !exists && !isTerminated(instance) {
+			event(instance, WatchTerminated, fmt.Sprintf("%s → gone", instance.GetMetadata("instance_state")))
+		}
+	}
+
+	return events
+}
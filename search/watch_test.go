@@ -0,0 +1,245 @@
+package search
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWatchTarget(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+		want   string
+		ok     bool
+	}{
+		{name: "Instance ID", target: "i-0123456789abcdef0", want: "i-0123456789abcdef0", ok: true},
+		{name: "Tag", target: "tag:Role=db", want: "tag:Role=db", ok: true},
+		{name: "Whitespace is trimmed", target: "  tag:Role=db ", want: "tag:Role=db", ok: true},
+		{name: "Tag values can contain =", target: "tag:Query=a=b", want: "tag:Query=a=b", ok: true},
+		{name: "Tag without a value", target: "tag:Role=", ok: false},
+		{name: "Tag without a key", target: "tag:=db", ok: false},
+		{name: "Tag without =", target: "tag:Role", ok: false},
+		{name: "Anything else", target: "web-production", ok: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := ParseWatchTarget(c.target)
+			if got != c.want || ok != c.ok {
+				t.Errorf("expected %q, %t, got %q, %t", c.want, c.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestSubscriptionStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "subscriptions.json")
+	createdAt := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	subscription := func(channelID, target string) Subscription {
+		return Subscription{ChannelID: channelID, Target: target, CreatedBy: "U0123456789", CreatedAt: createdAt}
+	}
+
+	store, err := NewSubscriptionStore(path)
+	if err != nil {
+		t.Fatalf("expected a missing file to be fine, got %s", err)
+	}
+
+	cases := []struct {
+		name    string
+		change  func(*SubscriptionStore) (bool, error)
+		changed bool
+		want    map[string][]Subscription
+	}{
+		{
+			name:    "Watching an instance",
+			change:  func(s *SubscriptionStore) (bool, error) { return s.Add(subscription("C1", "i-0123456789abcdef0")) },
+			changed: true,
+			want:    map[string][]Subscription{"C1": {subscription("C1", "i-0123456789abcdef0")}, "C2": {}},
+		},
+		{
+			name:    "Watching a tag in another channel",
+			change:  func(s *SubscriptionStore) (bool, error) { return s.Add(subscription("C2", "tag:Role=db")) },
+			changed: true,
+			want: map[string][]Subscription{
+				"C1": {subscription("C1", "i-0123456789abcdef0")},
+				"C2": {subscription("C2", "tag:Role=db")},
+			},
+		},
+		{
+			name:    "Watching the same thing twice",
+			change:  func(s *SubscriptionStore) (bool, error) { return s.Add(subscription("C1", "i-0123456789abcdef0")) },
+			changed: false,
+			want: map[string][]Subscription{
+				"C1": {subscription("C1", "i-0123456789abcdef0")},
+				"C2": {subscription("C2", "tag:Role=db")},
+			},
+		},
+		{
+			name:    "Unwatching something another channel is watching",
+			change:  func(s *SubscriptionStore) (bool, error) { return s.Remove("C1", "tag:Role=db") },
+			changed: false,
+			want: map[string][]Subscription{
+				"C1": {subscription("C1", "i-0123456789abcdef0")},
+				"C2": {subscription("C2", "tag:Role=db")},
+			},
+		},
+		{
+			name:    "Unwatching",
+			change:  func(s *SubscriptionStore) (bool, error) { return s.Remove("C1", "i-0123456789abcdef0") },
+			changed: true,
+			want:    map[string][]Subscription{"C1": {}, "C2": {subscription("C2", "tag:Role=db")}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			changed, err := c.change(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != c.changed {
+				t.Errorf("expected changed to be %t, got %t", c.changed, changed)
+			}
+
+			// A restart should see exactly what the running store does
+			reloaded, err := NewSubscriptionStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for channelID, want := range c.want {
+				if got := store.List(channelID); !reflect.DeepEqual(got, want) {
+					t.Errorf("expected %s to be watching %+v, got %+v", channelID, want, got)
+				}
+				if got := reloaded.List(channelID); !reflect.DeepEqual(got, want) {
+					t.Errorf("expected %s to be watching %+v after a restart, got %+v", channelID, want, got)
+				}
+			}
+
+			// Saves are written to a temporary file and renamed over the
+			// old one, which shouldn't be left behind
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 || files[0].Name() != "subscriptions.json" {
+				names := []string{}
+				for _, file := range files {
+					names = append(names, file.Name())
+				}
+				t.Errorf("expected only subscriptions.json to be written, got %q", names)
+			}
+		})
+	}
+}
+
+func TestNewSubscriptionStoreWithACorruptFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "subscriptions.json")
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewSubscriptionStore(path); err == nil {
+		t.Errorf("expected an error reading a corrupt file")
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	since := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+
+	cases := []struct {
+		name   string
+		before []Result
+		after  []Result
+		want   map[string][]string
+	}{
+		{
+			name:   "Nothing changed",
+			before: []Result{testInstance("i-1", map[string][]string{"system_status": {"ok"}})},
+			after:  []Result{testInstance("i-1", map[string][]string{"system_status": {"ok"}})},
+			want:   map[string][]string{},
+		},
+		{
+			name:   "Stopped",
+			before: []Result{testInstance("i-1", nil)},
+			after:  []Result{testInstance("i-1", map[string][]string{"instance_state": {"stopped"}})},
+			want:   map[string][]string{"i-1": {WatchStateChanged, "running → stopped"}},
+		},
+		{
+			name:   "Terminated",
+			before: []Result{testInstance("i-1", map[string][]string{"instance_state": {"shutting-down"}})},
+			after:  []Result{testInstance("i-1", map[string][]string{"instance_state": {"terminated"}})},
+			want:   map[string][]string{"i-1": {WatchTerminated, "shutting-down → terminated"}},
+		},
+		{
+			name:   "Disappeared from DescribeInstances",
+			before: []Result{testInstance("i-1", nil), testInstance("i-2", nil)},
+			after:  []Result{testInstance("i-2", nil)},
+			want:   map[string][]string{"i-1": {WatchTerminated, "running → gone"}},
+		},
+		{
+			name:   "Disappeared after being seen terminated",
+			before: []Result{testInstance("i-1", map[string][]string{"instance_state": {"terminated"}})},
+			after:  []Result{},
+			want:   map[string][]string{},
+		},
+		{
+			name:   "Launched",
+			before: []Result{},
+			after:  []Result{testInstance("i-1", nil)},
+			want:   map[string][]string{},
+		},
+		{
+			name:   "Status check became impaired",
+			before: []Result{testInstance("i-1", map[string][]string{"instance_status": {"ok"}})},
+			after:  []Result{testInstance("i-1", map[string][]string{"instance_status": {"impaired"}})},
+			want:   map[string][]string{"i-1": {WatchStatusCheckFailed, "instance status is impaired"}},
+		},
+		{
+			name:   "Status check still impaired",
+			before: []Result{testInstance("i-1", map[string][]string{"system_status": {"impaired"}})},
+			after:  []Result{testInstance("i-1", map[string][]string{"system_status": {"impaired"}})},
+			want:   map[string][]string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			events := watchEvents(testSnapshot(since, c.before...), testSnapshot(until, c.after...))
+
+			got := map[string][]string{}
+			for _, event := range events {
+				if event.Kind != "watch.event" {
+					t.Errorf("expected a watch.event, got %s", event.Kind)
+				}
+				if indexedAt := event.GetMetadata("indexed_at"); indexedAt != until.Format(time.RFC3339) {
+					t.Errorf("expected the event to be from the new snapshot, got %q", indexedAt)
+				}
+
+				id := event.GetMetadata("instance_id")
+				got[id] = append(got[id], event.GetMetadata("event"), event.GetMetadata("details"))
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("expected %q, got %q", c.want, got)
+			}
+		})
+	}
+}
@@ -3,7 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/geckoboard/slash-infra/search"
 	"github.com/geckoboard/slash-infra/slackutil"
@@ -13,8 +17,64 @@ import (
 func makeHttpHandler() *httprouter.Router {
 	router := httprouter.New()
 
+	accounts := search.AccountsFromEnvironment()
+	templates := resultTemplates()
+
+	// The inventory is crawled in the background for as long as the app runs
+	store := snapshotStore()
+	interval := inventoryRefreshInterval()
+	var inventory *search.Inventory
+	if interval > 0 {
+		inventory = search.NewInventory(accounts, store)
+	}
+
+	// Watchers need to be listening before the first crawl finishes
+	subscriptions := startWatching(inventory)
+	if inventory != nil {
+		go inventory.Run(context.Background(), interval)
+	}
+
+	ec2Resolver := search.NewEc2(accounts, inventory)
+	elbResolver := search.NewElb(accounts)
+	acmResolver := search.NewAcm(accounts)
+
+	resolvers := []search.Resolver{
+		ec2Resolver,
+		elbResolver,
+		search.NewRoute53(accounts, ec2Resolver, elbResolver),
+		search.NewAutoscaling(accounts),
+		search.NewEcs(accounts),
+		search.NewLambda(accounts),
+		search.NewS3(accounts),
+		search.NewElastiCache(accounts),
+		search.NewSqs(accounts),
+		search.NewSns(accounts),
+		search.NewDynamoDB(accounts),
+		acmResolver,
+	}
+	resolvers = append(resolvers, search.NewCloudFormation(accounts, resolvers...))
+	if store != nil {
+		resolvers = append(resolvers, search.NewHistory(store))
+		resolvers = append(resolvers, search.NewChanges(store))
+	}
+	policy := tagPolicy()
+	resolvers = append(resolvers, search.NewCompliance(policy, inventory))
+	resolvers = append(resolvers, search.NewLinks(linkTemplates()))
+	if inventory != nil {
+		hygiene := search.NewHygiene(inventory, acmResolver, policy)
+		resolvers = append(resolvers, hygiene)
+		startHygieneReports(hygiene, templates)
+	}
+
+	searcher := search.NewSearcher(accounts, resolvers...)
+	if ttl := cacheTTL(); ttl > 0 {
+		searcher.CacheResults(ttl)
+	}
+
 	s := httpServer{
-		ec2Resolver: search.NewEc2(),
+		searcher:      searcher,
+		subscriptions: subscriptions,
+		templates:     templates,
 	}
 
 	router.POST("/slack/infra-search", s.whatIsHandler)
@@ -22,8 +82,119 @@ func makeHttpHandler() *httprouter.Router {
 	return router
 }
 
+// inventoryRefreshInterval reads how often to crawl the inventory from
+// INVENTORY_REFRESH_INTERVAL, e.g. `10m`. Setting it to `0` turns the
+// inventory off.
+func inventoryRefreshInterval() time.Duration {
+	setting := os.Getenv("INVENTORY_REFRESH_INTERVAL")
+	if setting == "" {
+		return search.DefaultInventoryRefreshInterval
+	}
+
+	interval, err := time.ParseDuration(setting)
+	if err != nil {
+		log.Printf("could not parse INVENTORY_REFRESH_INTERVAL %q, using the default: %s", setting, err)
+		return search.DefaultInventoryRefreshInterval
+	}
+
+	return interval
+}
+
+// cacheTTL reads how long to reuse search results for from
+// SEARCH_CACHE_TTL, e.g. `1m`. Setting it to `0` turns the cache off.
+func cacheTTL() time.Duration {
+	setting := os.Getenv("SEARCH_CACHE_TTL")
+	if setting == "" {
+		return search.DefaultCacheTTL
+	}
+
+	ttl, err := time.ParseDuration(setting)
+	if err != nil {
+		log.Printf("could not parse SEARCH_CACHE_TTL %q, using the default: %s", setting, err)
+		return search.DefaultCacheTTL
+	}
+
+	return ttl
+}
+
+// tagPolicy reads the tags resources should have from the JSON file in
+// TAG_POLICY_FILE. By default, instances need an Environment and a Role.
+func tagPolicy() *search.TagPolicy {
+	path := os.Getenv("TAG_POLICY_FILE")
+	if path == "" {
+		return search.DefaultTagPolicy()
+	}
+
+	policy, err := search.LoadTagPolicy(path)
+	if err != nil {
+		log.Printf("could not load TAG_POLICY_FILE, using the default: %s", err)
+		return search.DefaultTagPolicy()
+	}
+
+	return policy
+}
+
+// linkTemplates reads links to other tools, like dashboards and runbooks,
+// from the JSON file in LINK_TEMPLATES_FILE
+func linkTemplates() []search.LinkTemplate {
+	path := os.Getenv("LINK_TEMPLATES_FILE")
+	if path == "" {
+		return []search.LinkTemplate{}
+	}
+
+	templates, err := search.LoadLinkTemplates(path)
+	if err != nil {
+		log.Printf("could not load LINK_TEMPLATES_FILE, no links will be added: %s", err)
+		return []search.LinkTemplate{}
+	}
+
+	return templates
+}
+
+// resultTemplates reads how to lay out kinds of result from the JSON file in
+// RESULT_TEMPLATES_FILE. Kinds without a template use their formatter's
+// fields.
+func resultTemplates() map[string]search.ResultTemplate {
+	path := os.Getenv("RESULT_TEMPLATES_FILE")
+	if path == "" {
+		return map[string]search.ResultTemplate{}
+	}
+
+	templates, err := search.LoadResultTemplates(path)
+	if err != nil {
+		log.Printf("could not load RESULT_TEMPLATES_FILE, using the default formatting: %s", err)
+		return map[string]search.ResultTemplate{}
+	}
+
+	return templates
+}
+
+// snapshotStore keeps inventory snapshots in INVENTORY_SNAPSHOT_DIR, for
+// as long as INVENTORY_SNAPSHOT_RETENTION, e.g. `168h`. Snapshots aren't
+// kept if no directory is set.
+func snapshotStore() *search.SnapshotStore {
+	dir := os.Getenv("INVENTORY_SNAPSHOT_DIR")
+	if dir == "" {
+		return nil
+	}
+
+	retention := search.DefaultSnapshotRetention
+	if setting := os.Getenv("INVENTORY_SNAPSHOT_RETENTION"); setting != "" {
+		parsed, err := time.ParseDuration(setting)
+		if err != nil {
+			log.Printf("could not parse INVENTORY_SNAPSHOT_RETENTION %q, using the default: %s", setting, err)
+		} else {
+			retention = parsed
+		}
+	}
+
+	return search.NewSnapshotStore(dir, retention)
+}
+
 type httpServer struct {
-	ec2Resolver *search.EC2Resolver
+	searcher      *search.Searcher
+	subscriptions *search.SubscriptionStore
+	templates     map[string]search.ResultTemplate
 }
 
 func respondWithError(w http.ResponseWriter, statusCode int, msg string) {
@@ -59,6 +230,25 @@ func FormatEc2InstanceAsAttachment(instance search.Result) slackutil.Attachment
 			Short: true,
 		})
 	}
+	fields = append(fields, slackutil.Field{
+		Title: "AMI",
+		Value: instance.GetMetadata("ami_id"),
+		Short: true,
+	})
+	if volumes := instance.Metadata["block_devices"]; len(volumes) > 0 {
+		fields = append(fields, slackutil.Field{
+			Title: "Volumes",
+			Value: strings.Join(volumes, "\n"),
+			Short: true,
+		})
+	}
+	if groupLink := instance.GetLink("autoscaling_group"); groupLink != "" {
+		fields = append(fields, slackutil.Field{
+			Title: "Auto Scaling group",
+			Value: fmt.Sprintf("<%s|%s>", groupLink, instance.GetMetadata("tag:"+search.AutoScalingGroupTag)),
+			Short: true,
+		})
+	}
 	fields = append(fields, slackutil.Field{
 		Value: fmt.Sprintf("⏳ <%s|AWS config timeline>", instance.GetLink("config_timeline")),
 	})
@@ -73,6 +263,7 @@ func FormatEc2InstanceAsAttachment(instance search.Result) slackutil.Attachment
 			instance.GetMetadata("az"),
 		),
 		Fields:     fields,
+		Footer:     instance.GetMetadata("account"),
 		MarkdownIn: []string{"text"},
 	}
 }
@@ -84,25 +275,25 @@ func (h httpServer) whatIsHandler(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
+	if response, ok := h.handleWatchCommand(*command); ok {
+		slackutil.RespondWith(w, response)
+		return
+	}
+
 	findResources := slackutil.DelayedSlashResponse{
 		PendingResponse: slackutil.Response{
 			Text: "Hang on a jiffy while we look that up...",
 		},
 
 		Handler: func(ctx context.Context, req slackutil.SlashCommandRequest, resp slackutil.MessageResponder) {
-			resultSets := h.ec2Resolver.Search(ctx, command.Text)
+			resultSets := h.searcher.Search(ctx, command.Text)
 
 			response := slackutil.Response{
-				Attachments: []slackutil.Attachment{},
+				Attachments: formatResultSets(resultSets, h.templates),
 			}
 
-			for _, setOfResults := range resultSets {
-				if setOfResults.Kind == "ec2.instance" {
-					if len(setOfResults.Results) == 1 {
-						response.Attachments = append(response.Attachments, FormatEc2InstanceAsAttachment(setOfResults.Results[0]))
-					}
-				}
-
+			if len(response.Attachments) == 0 {
+				response.Text = fmt.Sprintf("Couldn't find anything matching `%s`", command.Text)
 			}
 
 			resp.PublicResponse(response)
@@ -0,0 +1,102 @@
+package slackutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+const postMessageURL = "https://slack.com/api/chat.postMessage"
+
+// Message is a message posted to a channel outside of a slash command,
+// either with chat.postMessage or an incoming webhook
+type Message struct {
+	Channel     string       `json:"channel,omitempty"`
+	Text        string       `json:"text"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Client posts messages to any channel the app's bot user has been
+// invited to, using a bot token (`xoxb-...`) with the `chat:write` scope
+type Client struct {
+	token string
+	// url is chat.postMessage's URL, which tests point at a fake Slack
+	url string
+}
+
+func NewClient(token string) *Client {
+	return &Client{token: token, url: postMessageURL}
+}
+
+// PostMessage posts a message to a channel, which can be a channel ID or
+// name
+// https://api.slack.com/methods/chat.postMessage
+func (c *Client) PostMessage(ctx context.Context, msg Message) error {
+	b, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequest("POST", c.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r = r.WithContext(ctx)
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	r.Header.Set("Authorization", "Bearer "+c.token)
+
+	apiResp, err := slackClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer apiResp.Body.Close()
+
+	// The Web API responds with 200 OK even if the message wasn't posted
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(apiResp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("could not read chat.postMessage response: %s", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("could not post message to %s: %s", msg.Channel, result.Error)
+	}
+
+	return nil
+}
+
+// PostToWebhook posts a message with an incoming webhook. Webhooks always
+// post to the channel they were created for, so msg.Channel is ignored.
+// https://api.slack.com/messaging/webhooks
+func PostToWebhook(ctx context.Context, webhookURL string, msg Message) error {
+	msg.Channel = ""
+
+	b, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequest("POST", webhookURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r = r.WithContext(ctx)
+	r.Header.Set("Content-Type", "application/json")
+
+	apiResp, err := slackClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer apiResp.Body.Close()
+
+	if apiResp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(apiResp.Body)
+		return fmt.Errorf("could not post message to webhook: %s %s", apiResp.Status, body)
+	}
+
+	return nil
+}
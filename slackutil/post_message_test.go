@@ -0,0 +1,70 @@
+package slackutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostMessage(t *testing.T) {
+	cases := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{
+			name:     "Posted",
+			response: `{"ok": true, "channel": "C0123456789", "ts": "1531420618.000100"}`,
+		},
+		{
+			name:     "Slack refused the message",
+			response: `{"ok": false, "error": "not_in_channel"}`,
+			wantErr:  "could not post message to C0123456789: not_in_channel",
+		},
+		{
+			name:     "Slack's response couldn't be read",
+			response: `<html>`,
+			wantErr:  "could not read chat.postMessage response: invalid character '<' looking for beginning of value",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				auth string
+				got  Message
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("expected a JSON message, got %s", err)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(c.response))
+			}))
+			defer server.Close()
+
+			client := NewClient("xoxb-test")
+			client.url = server.URL
+
+			err := client.PostMessage(context.Background(), Message{Channel: "C0123456789", Text: "i-0123456789abcdef0 is `stopped`"})
+
+			if c.wantErr == "" && err != nil {
+				t.Fatalf("expected the message to be posted, got %s", err)
+			}
+			if c.wantErr != "" && (err == nil || err.Error() != c.wantErr) {
+				t.Fatalf("expected error %q, got %v", c.wantErr, err)
+			}
+
+			if auth != "Bearer xoxb-test" {
+				t.Errorf("expected the bot token to be sent, got %q", auth)
+			}
+			if got.Channel != "C0123456789" || got.Text != "i-0123456789abcdef0 is `stopped`" {
+				t.Errorf("expected the message to be sent as is, got %+v", got)
+			}
+		})
+	}
+}
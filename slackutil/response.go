@@ -40,6 +40,18 @@ type Attachment struct {
 	Footer        string   `json:"footer,omitempty"`
 	FooterIcon    string   `json:"footer_icon,omitempty"`
 	Timestamp     int64    `json:"ts,omitempty"`
+	CallbackID    string   `json:"callback_id,omitempty"`
+	Actions       []Action `json:"actions,omitempty"`
+}
+
+// Action is a button on an attachment. Buttons with a URL open it in the
+// user's browser, and don't need an interactivity endpoint.
+// https://api.slack.com/legacy/message-buttons
+type Action struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	URL   string `json:"url,omitempty"`
+	Style string `json:"style,omitempty"`
 }
 
 // Field is a field attachment
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geckoboard/slash-infra/search"
+	"github.com/geckoboard/slash-infra/slackutil"
+)
+
+// templateFields lays out a result's metadata and links as the template
+// says. Metadata the result doesn't have is left out.
+func templateFields(template search.ResultTemplate, result search.Result) []slackutil.Field {
+	fields := []slackutil.Field{}
+
+	for _, field := range template.Fields {
+		values := result.Metadata[field.Key]
+		if len(values) == 0 || strings.Join(values, "") == "" {
+			continue
+		}
+
+		fields = append(fields, slackutil.Field{
+			Title: field.Label,
+			Value: strings.Join(values, ", "),
+			Short: !field.Wide,
+		})
+	}
+
+	links := []string{}
+	for _, link := range template.Links {
+		if url := result.GetLink(link.Key); url != "" {
+			links = append(links, fmt.Sprintf("<%s|%s>", url, link.Label))
+		}
+	}
+	if len(links) > 0 {
+		fields = append(fields, slackutil.Field{
+			Value: strings.Join(links, " · "),
+		})
+	}
+
+	return fields
+}
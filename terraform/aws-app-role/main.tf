@@ -22,7 +22,71 @@ resource "aws_iam_role_policy" "allow-read-only-access" {
 
 data "aws_iam_policy_document" "allow-read-only-access" {
   statement {
-    actions   = ["ec2:DescribeInstances"]
+    actions = [
+      "ec2:DescribeInstances",
+      "ec2:DescribeInstanceStatus",
+      "ec2:DescribeNetworkInterfaces",
+      "ec2:DescribeVolumes",
+      "ec2:DescribeSnapshots",
+      "ec2:DescribeImages",
+      "ec2:DescribeAddresses",
+      "ec2:DescribeNatGateways",
+      "elasticloadbalancing:DescribeLoadBalancers",
+      "route53:ListHostedZones",
+      "route53:ListResourceRecordSets",
+      "autoscaling:DescribeAutoScalingGroups",
+      "autoscaling:DescribeAutoScalingInstances",
+      "autoscaling:DescribeScalingActivities",
+      "ecs:ListClusters",
+      "ecs:ListTasks",
+      "ecs:ListContainerInstances",
+      "ecs:DescribeTasks",
+      "ecs:DescribeServices",
+      "ecs:DescribeTaskDefinition",
+      "lambda:GetFunctionConfiguration",
+      "lambda:ListAliases",
+      "lambda:ListVersionsByFunction",
+      "elasticache:DescribeCacheClusters",
+      "elasticache:DescribeReplicationGroups",
+      "sqs:GetQueueUrl",
+      "sqs:GetQueueAttributes",
+      "sqs:ListQueueTags",
+      "sns:GetTopicAttributes",
+      "sns:ListSubscriptionsByTopic",
+      "dynamodb:DescribeTable",
+      "dynamodb:DescribeTimeToLive",
+      "dynamodb:DescribeContinuousBackups",
+      "dynamodb:ListTagsOfResource",
+      "dynamodb:DescribeGlobalTable",
+      "acm:ListCertificates",
+      "acm:DescribeCertificate",
+      "cloudformation:DescribeStacks",
+      "cloudformation:ListStackResources",
+    ]
+    resources = ["*"]
+  }
+
+  # The S3 resolver only makes these read only calls, none of which can
+  # read the contents of a bucket:
+  #
+  # ListBuckets           -> s3:ListAllMyBuckets
+  # GetBucketLocation     -> s3:GetBucketLocation
+  # GetBucketVersioning   -> s3:GetBucketVersioning
+  # GetBucketEncryption   -> s3:GetEncryptionConfiguration
+  # GetPublicAccessBlock  -> s3:GetBucketPublicAccessBlock
+  # GetBucketPolicyStatus -> s3:GetBucketPolicyStatus
+  # GetBucketTagging      -> s3:GetBucketTagging
+  statement {
+    sid     = "S3BucketPosture"
+    actions = [
+      "s3:ListAllMyBuckets",
+      "s3:GetBucketLocation",
+      "s3:GetBucketVersioning",
+      "s3:GetEncryptionConfiguration",
+      "s3:GetBucketPublicAccessBlock",
+      "s3:GetBucketPolicyStatus",
+      "s3:GetBucketTagging",
+    ]
     resources = ["*"]
   }
 }
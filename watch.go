@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	bugsnag "github.com/bugsnag/bugsnag-go"
+	"github.com/geckoboard/slash-infra/search"
+	"github.com/geckoboard/slash-infra/slackutil"
+)
+
+// WatchNotificationTimeout caps how long posting a change to a channel can
+// take
+const WatchNotificationTimeout = 10 * time.Second
+
+// startWatching lets channels watch instances in the inventory, posting to
+// them with SLACK_BOT_TOKEN, and saving subscriptions to the file in
+// WATCH_SUBSCRIPTIONS_FILE. It returns nil if either isn't set, as we'd
+// have no way of telling anyone about changes, or of remembering who to
+// tell after a restart.
+func startWatching(inventory *search.Inventory) *search.SubscriptionStore {
+	token := os.Getenv("SLACK_BOT_TOKEN")
+	if token == "" || inventory == nil {
+		return nil
+	}
+
+	// The working directory isn't a safe default, as it's wiped whenever
+	// e.g. a Heroku dyno restarts
+	path := os.Getenv("WATCH_SUBSCRIPTIONS_FILE")
+	if path == "" {
+		log.Print("watching instances is turned off, as WATCH_SUBSCRIPTIONS_FILE isn't set")
+		return nil
+	}
+
+	subscriptions, err := search.NewSubscriptionStore(path)
+	if err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+		return nil
+	}
+
+	client := slackutil.NewClient(token)
+	watcher := search.NewWatcher(subscriptions, func(subscription search.Subscription, event search.Result) {
+		msg := slackutil.Message{
+			Channel:     subscription.ChannelID,
+			Attachments: []slackutil.Attachment{FormatWatchEventAsAttachment(subscription, event)},
+		}
+
+		// Posts are sent in the background, so that a slow response from
+		// Slack doesn't hold up the inventory refresh
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), WatchNotificationTimeout)
+			defer cancel()
+
+			if err := client.PostMessage(ctx, msg); err != nil {
+				bugsnag.Notify(err)
+				log.Print(err)
+			}
+		}()
+	})
+	inventory.OnRefresh(watcher.Compare)
+
+	return subscriptions
+}
+
+// handleWatchCommand deals with `watch {target}`, `watch list` and
+// `unwatch {target}`. It's false if the command isn't one of those.
+func (h httpServer) handleWatchCommand(command slackutil.SlashCommandRequest) (slackutil.Response, bool) {
+	fields := strings.Fields(command.Text)
+	if len(fields) != 2 || (fields[0] != "watch" && fields[0] != "unwatch") {
+		return slackutil.Response{}, false
+	}
+
+	if h.subscriptions == nil {
+		return slackutil.Response{
+			ResponseType: slackutil.ResponseEphemeral,
+			Text:         "Watching isn't set up, it needs the inventory, a `SLACK_BOT_TOKEN` and a `WATCH_SUBSCRIPTIONS_FILE`",
+		}, true
+	}
+
+	if fields[0] == "watch" && fields[1] == "list" {
+		return h.listSubscriptions(command), true
+	}
+
+	target, ok := search.ParseWatchTarget(fields[1])
+	if !ok {
+		return slackutil.Response{
+			ResponseType: slackutil.ResponseEphemeral,
+			Text:         fmt.Sprintf("Can't watch `%s`, try an instance ID like `i-0123456789abcdef0` or a tag like `tag:Role=db`", fields[1]),
+		}, true
+	}
+
+	if fields[0] == "unwatch" {
+		return h.removeSubscription(command, target), true
+	}
+
+	return h.addSubscription(command, target), true
+}
+
+func (h httpServer) addSubscription(command slackutil.SlashCommandRequest, target string) slackutil.Response {
+	added, err := h.subscriptions.Add(search.Subscription{
+		ChannelID: command.ChannelID,
+		Target:    target,
+		CreatedBy: command.UserName,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+		return slackutil.Response{ResponseType: slackutil.ResponseEphemeral, Text: "Sorry, that subscription couldn't be saved"}
+	}
+
+	if !added {
+		return slackutil.Response{ResponseType: slackutil.ResponseEphemeral, Text: fmt.Sprintf("This channel is already watching `%s`", target)}
+	}
+
+	return slackutil.Response{
+		ResponseType: slackutil.ResponseInChannel,
+		Text:         fmt.Sprintf(":eyes: This channel will be told when `%s` changes state, is terminated or fails a status check", target),
+	}
+}
+
+func (h httpServer) removeSubscription(command slackutil.SlashCommandRequest, target string) slackutil.Response {
+	removed, err := h.subscriptions.Remove(command.ChannelID, target)
+	if err != nil {
+		bugsnag.Notify(err)
+		log.Print(err)
+		return slackutil.Response{ResponseType: slackutil.ResponseEphemeral, Text: "Sorry, that subscription couldn't be removed"}
+	}
+
+	if !removed {
+		return slackutil.Response{ResponseType: slackutil.ResponseEphemeral, Text: fmt.Sprintf("This channel isn't watching `%s`", target)}
+	}
+
+	return slackutil.Response{
+		ResponseType: slackutil.ResponseInChannel,
+		Text:         fmt.Sprintf("This channel has stopped watching `%s`", target),
+	}
+}
+
+func (h httpServer) listSubscriptions(command slackutil.SlashCommandRequest) slackutil.Response {
+	subscriptions := h.subscriptions.List(command.ChannelID)
+	if len(subscriptions) == 0 {
+		return slackutil.Response{ResponseType: slackutil.ResponseEphemeral, Text: "This channel isn't watching anything"}
+	}
+
+	lines := []string{}
+	for _, subscription := range subscriptions {
+		lines = append(lines, fmt.Sprintf("`%s`, added by %s on %s", subscription.Target, subscription.CreatedBy, subscription.CreatedAt.Format("2006-01-02")))
+	}
+
+	return slackutil.Response{
+		ResponseType: slackutil.ResponseEphemeral,
+		Text:         "This channel is watching:\n" + strings.Join(lines, "\n"),
+	}
+}